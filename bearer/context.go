@@ -0,0 +1,32 @@
+package bearer
+
+import (
+	"context"
+
+	"github.com/radekg/app-kit-tokens/tokens"
+)
+
+type contextKey int
+
+const (
+	accessTokenContextKey contextKey = iota
+	rawTokenContextKey
+)
+
+// WithAccessToken returns a copy of the context carrying the access token and its raw form.
+func WithAccessToken(ctx context.Context, accessToken tokens.AccessToken, rawToken string) context.Context {
+	ctx = context.WithValue(ctx, accessTokenContextKey, accessToken)
+	return context.WithValue(ctx, rawTokenContextKey, rawToken)
+}
+
+// AccessTokenFromContext returns the access token stored in the context by the middleware.
+func AccessTokenFromContext(ctx context.Context) (tokens.AccessToken, bool) {
+	accessToken, ok := ctx.Value(accessTokenContextKey).(tokens.AccessToken)
+	return accessToken, ok
+}
+
+// RawTokenFromContext returns the raw access token stored in the context by the middleware.
+func RawTokenFromContext(ctx context.Context) (string, bool) {
+	rawToken, ok := ctx.Value(rawTokenContextKey).(string)
+	return rawToken, ok
+}
@@ -0,0 +1,62 @@
+package bearer
+
+import (
+	"github.com/radekg/app-kit-tokens/dpop"
+	"github.com/radekg/app-kit-tokens/mtls"
+	"github.com/radekg/app-kit-tokens/tokens"
+)
+
+// Fixed error descriptions of the errors not defined by this module:
+const (
+	// DescriptionInvalidBinding describes a failed certificate binding verification.
+	DescriptionInvalidBinding = "token not bound to the client certificate"
+	// DescriptionInvalidClaims describes a failed claims validation.
+	DescriptionInvalidClaims = "token claims invalid"
+	// DescriptionInvalidProof describes a failed DPoP proof verification.
+	DescriptionInvalidProof = "invalid dpop proof"
+	// DescriptionInvalidSignature describes a token which cannot be parsed or verified.
+	DescriptionInvalidSignature = "token signature invalid"
+	// DescriptionNotAuthorized describes a token not permitting the request.
+	DescriptionNotAuthorized = "token does not permit the request"
+)
+
+// describedErrors are the errors whose messages are safe to send to unauthenticated clients.
+var describedErrors = []error{
+	ErrMalformedAuthorization,
+	ErrMultipleTokens,
+	ErrTokenMissing,
+	dpop.ErrAccessTokenHashMismatch,
+	dpop.ErrAlgorithmNotAllowed,
+	dpop.ErrInvalidProof,
+	dpop.ErrKeyBindingMismatch,
+	dpop.ErrMethodMismatch,
+	dpop.ErrMultipleProofs,
+	dpop.ErrProofExpired,
+	dpop.ErrProofMissing,
+	dpop.ErrProofReplayed,
+	dpop.ErrTokenMissing,
+	dpop.ErrURIMismatch,
+	dpop.ErrUseNonce,
+	mtls.ErrCertificateBindingMismatch,
+	mtls.ErrClientCertificateMissing,
+	mtls.ErrNotCertificateBound,
+	tokens.ErrAudienceMismatch,
+	tokens.ErrExpirationMissing,
+	tokens.ErrIssuedInFuture,
+	tokens.ErrIssuerMismatch,
+	tokens.ErrResourceNotInAudience,
+	tokens.ErrTokenExpired,
+	tokens.ErrTokenNotValidYet,
+}
+
+// ErrorDescription returns the error description sent to the client for an authentication error.
+// Errors of the tokens, dpop, mtls and bearer packages are described by their fixed messages,
+// any other error might carry internal details and is described by the fallback.
+func ErrorDescription(err error, fallback string) string {
+	for _, described := range describedErrors {
+		if err == described {
+			return err.Error()
+		}
+	}
+	return fallback
+}
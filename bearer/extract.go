@@ -0,0 +1,97 @@
+package bearer
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrMalformedAuthorization indicates an Authorization header which is not a bearer token.
+	ErrMalformedAuthorization = errMalformedAuthorization()
+	// ErrMultipleTokens indicates a request using more than one method to transmit the token.
+	ErrMultipleTokens = errMultipleTokens()
+	// ErrTokenMissing indicates a request without a token.
+	ErrTokenMissing = errTokenMissing()
+)
+
+func errMalformedAuthorization() error { return errors.New("malformed authorization header") }
+func errMultipleTokens() error         { return errors.New("multiple token transmission methods used") }
+func errTokenMissing() error           { return errors.New("token missing") }
+
+const accessTokenParameter = "access_token"
+
+// Extraction selects the RFC 6750 token transmission methods accepted in addition to
+// the Authorization request header.
+type Extraction struct {
+	// FormBody: accept the access_token form-encoded body parameter.
+	FormBody bool
+	// Query: accept the access_token URI query parameter.
+	Query bool
+}
+
+// ExtractToken extracts the bearer token from the request.
+// RFC 6750 forbids using more than one method in a single request, ErrMultipleTokens is returned
+// when the token is transmitted with more than one of the accepted methods.
+func ExtractToken(r *http.Request, extraction *Extraction) (string, error) {
+	if extraction == nil {
+		extraction = &Extraction{}
+	}
+	found := []string{}
+
+	if header := r.Header.Get("Authorization"); header != "" {
+		token, err := parseAuthorizationHeader(header)
+		if err != nil {
+			return "", err
+		}
+		found = append(found, token)
+	}
+	if extraction.FormBody && isFormEncoded(r) {
+		if err := r.ParseForm(); err != nil {
+			return "", err
+		}
+		if values, ok := r.PostForm[accessTokenParameter]; ok {
+			found = append(found, values...)
+		}
+	}
+	if extraction.Query {
+		if values, ok := r.URL.Query()[accessTokenParameter]; ok {
+			found = append(found, values...)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return "", ErrTokenMissing
+	case 1:
+		if found[0] == "" {
+			return "", ErrTokenMissing
+		}
+		return found[0], nil
+	default:
+		return "", ErrMultipleTokens
+	}
+}
+
+func parseAuthorizationHeader(header string) (string, error) {
+	parts := strings.SplitN(header, " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return "", ErrMalformedAuthorization
+	}
+	token := strings.TrimSpace(parts[1])
+	if token == "" || strings.ContainsAny(token, " \t") {
+		return "", ErrMalformedAuthorization
+	}
+	return token, nil
+}
+
+// isFormEncoded checks the RFC 6750 form-encoded body parameter requirements:
+// a single-part application/x-www-form-urlencoded body of a method with defined body semantics.
+func isFormEncoded(r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/x-www-form-urlencoded"
+}
@@ -0,0 +1,268 @@
+package bearer
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/radekg/app-kit-tokens/dpop"
+	"github.com/radekg/app-kit-tokens/jwks"
+	"github.com/radekg/app-kit-tokens/mtls"
+	"github.com/radekg/app-kit-tokens/tokens"
+)
+
+// RFC 6750 error codes:
+const (
+	// ErrorCodeInsufficientScope indicates a token without the scopes required by the resource.
+	ErrorCodeInsufficientScope = "insufficient_scope"
+	// ErrorCodeInvalidRequest indicates a malformed request.
+	ErrorCodeInvalidRequest = "invalid_request"
+	// ErrorCodeInvalidToken indicates an expired, revoked, malformed or otherwise invalid token.
+	ErrorCodeInvalidToken = "invalid_token"
+)
+
+// Challenge represents the RFC 6750 WWW-Authenticate challenge.
+type Challenge struct {
+	// Algorithms: the accepted DPoP proof algorithms advertised with the algs attribute, optional.
+	Algorithms       []string
+	ErrorCode        string
+	ErrorDescription string
+	// Nonce: the nonce sent in the DPoP-Nonce header, optional.
+	Nonce string
+	Realm string
+	// Scheme: the authentication scheme, Bearer if empty.
+	Scheme string
+	Scope  tokens.Scopes
+}
+
+// Status returns the HTTP status code for the challenge error code.
+func (c *Challenge) Status() int {
+	switch c.ErrorCode {
+	case ErrorCodeInvalidRequest:
+		return http.StatusBadRequest
+	case ErrorCodeInsufficientScope:
+		return http.StatusForbidden
+	default:
+		return http.StatusUnauthorized
+	}
+}
+
+// String returns the WWW-Authenticate header value.
+func (c *Challenge) String() string {
+	attributes := []string{}
+	if c.Realm != "" {
+		attributes = append(attributes, fmt.Sprintf(`realm="%s"`, quotable(c.Realm)))
+	}
+	if c.ErrorCode != "" {
+		attributes = append(attributes, fmt.Sprintf(`error="%s"`, c.ErrorCode))
+	}
+	if c.ErrorDescription != "" {
+		attributes = append(attributes, fmt.Sprintf(`error_description="%s"`, quotable(c.ErrorDescription)))
+	}
+	if len(c.Scope) > 0 {
+		attributes = append(attributes, fmt.Sprintf(`scope="%s"`, quotable(c.Scope.String())))
+	}
+	if len(c.Algorithms) > 0 {
+		attributes = append(attributes, fmt.Sprintf(`algs="%s"`, quotable(strings.Join(c.Algorithms, " "))))
+	}
+	scheme := c.Scheme
+	if scheme == "" {
+		scheme = "Bearer"
+	}
+	if len(attributes) == 0 {
+		return scheme
+	}
+	return scheme + " " + strings.Join(attributes, ", ")
+}
+
+// quotable removes the characters RFC 6750 does not allow in the attribute values.
+func quotable(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return -1
+		}
+		return r
+	}, value)
+}
+
+// WriteChallenge writes the challenge response.
+func WriteChallenge(w http.ResponseWriter, challenge *Challenge) {
+	if challenge.Nonce != "" {
+		w.Header().Set(dpop.NonceHeader, challenge.Nonce)
+	}
+	w.Header().Set("WWW-Authenticate", challenge.String())
+	w.WriteHeader(challenge.Status())
+}
+
+// Authorizer authorizes authenticated requests.
+type Authorizer interface {
+	// Authorize returns an error if the access token does not permit the request.
+	// If the error has a RequiredScopes() tokens.Scopes method, the scopes are advertised in the challenge.
+	Authorize(r *http.Request, accessToken tokens.AccessToken) error
+}
+
+// Config represents the middleware configuration.
+type Config struct {
+	// Authorizer: authorizes the request after authentication, optional.
+	// Requests not authorized are answered with the insufficient_scope challenge.
+	Authorizer Authorizer
+	// CertificateBinding: verifies RFC 8705 certificate-bound tokens against the TLS client certificate, optional.
+	CertificateBinding *mtls.Binding
+	// DPoP: accepts tokens sent with the RFC 9449 DPoP scheme and verifies their proofs, optional.
+	// Tokens bound to a DPoP key with the cnf jkt claim are rejected when sent with the Bearer scheme,
+	// whether this is set or not.
+	DPoP dpop.Verifier
+	// DPoPNonce: returns the nonce sent in the DPoP-Nonce header of DPoP challenges, optional.
+	// Set it together with the Nonce function of the verifier configuration.
+	DPoPNonce func() string
+	// ErrorLog: logs the errors of rejected requests, the challenges carry fixed descriptions only,
+	// see ErrorDescription. Defaults to the standard logger of the log package.
+	ErrorLog *log.Logger
+	// Extraction: token transmission methods accepted in addition to the Authorization header.
+	Extraction *Extraction
+	// JWKS: verifies the token signature.
+	JWKS jwks.JWKS
+	// Realm: the protection space advertised in the challenge, optional.
+	Realm string
+	// RequiredScopes: scopes the token must carry, optional. A scope may be a pattern,
+	// see tokens.Scopes.Grants.
+	RequiredScopes tokens.Scopes
+	// Validator: validates the token claims, defaults to tokens.DefaultClaimsValidator(nil)
+	// which rejects tokens without exp, expired tokens and tokens not valid yet.
+	Validator tokens.ClaimsValidator
+}
+
+// Middleware authenticates requests with bearer tokens.
+type Middleware interface {
+	// Authenticate extracts and verifies the token, returns a challenge on failure.
+	Authenticate(r *http.Request) (tokens.AccessToken, string, *Challenge)
+	// Handler returns a handler calling the next handler with the access token in the request context.
+	Handler(next http.Handler) http.Handler
+}
+
+type defaultMiddleware struct {
+	config    *Config
+	validator tokens.ClaimsValidator
+}
+
+// New returns a middleware for the configuration.
+func New(config *Config) Middleware {
+	validator := config.Validator
+	if validator == nil {
+		validator = tokens.DefaultClaimsValidator(nil)
+	}
+	return &defaultMiddleware{config: config, validator: validator}
+}
+
+func (m *defaultMiddleware) Authenticate(r *http.Request) (tokens.AccessToken, string, *Challenge) {
+	rawToken, usesDPoP, err := m.extractToken(r)
+	if err != nil {
+		if err == ErrTokenMissing {
+			// RFC 6750: no error information for requests lacking any authentication information
+			return nil, "", &Challenge{Realm: m.config.Realm}
+		}
+		return nil, "", m.reject(ErrorCodeInvalidRequest, err, ErrMalformedAuthorization.Error())
+	}
+	read := m.config.JWKS.ReadSigned(rawToken)
+	if read.Error() != nil {
+		return nil, "", m.reject(ErrorCodeInvalidToken, read.Error(), DescriptionInvalidSignature)
+	}
+	if err := m.validator.Validate(read.Claims()); err != nil {
+		return nil, "", m.reject(ErrorCodeInvalidToken, err, DescriptionInvalidClaims)
+	}
+	accessToken := tokens.DefaultAccessToken(read.Claims())
+	if usesDPoP {
+		if _, err := m.config.DPoP.VerifyRequest(r, rawToken, accessToken); err != nil {
+			return nil, "", m.dpopChallenge(err)
+		}
+	} else if cnf, _ := accessToken.Cnf(); cnf.HasClaim("jkt") {
+		// a DPoP-bound token without the proof is a replayed token:
+		return nil, "", m.challenge(ErrorCodeInvalidToken, "token is bound to a DPoP key")
+	}
+	if m.config.CertificateBinding != nil {
+		if err := m.config.CertificateBinding.Verify(r, accessToken); err != nil {
+			return nil, "", m.reject(ErrorCodeInvalidToken, err, DescriptionInvalidBinding)
+		}
+	}
+	if len(m.config.RequiredScopes) > 0 {
+		scopes, _ := accessToken.Scopes()
+		if !scopes.Grants(m.config.RequiredScopes...) {
+			challenge := m.challenge(ErrorCodeInsufficientScope, "token does not carry the required scopes")
+			challenge.Scope = m.config.RequiredScopes
+			return nil, "", challenge
+		}
+	}
+	if m.config.Authorizer != nil {
+		if err := m.config.Authorizer.Authorize(r, accessToken); err != nil {
+			challenge := m.reject(ErrorCodeInsufficientScope, err, DescriptionNotAuthorized)
+			if scoped, ok := err.(interface{ RequiredScopes() tokens.Scopes }); ok {
+				challenge.Scope = scoped.RequiredScopes()
+			}
+			return nil, "", challenge
+		}
+	}
+	return accessToken, rawToken, nil
+}
+
+func (m *defaultMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accessToken, rawToken, challenge := m.Authenticate(r)
+		if challenge != nil {
+			WriteChallenge(w, challenge)
+			return
+		}
+		if m.config.Extraction != nil && m.config.Extraction.Query {
+			// RFC 6750: responses to requests possibly carrying the token in the URI must not be shared
+			w.Header().Set("Cache-Control", "private")
+		}
+		next.ServeHTTP(w, r.WithContext(WithAccessToken(r.Context(), accessToken, rawToken)))
+	})
+}
+
+// extractToken extracts the token sent with the DPoP scheme when DPoP is configured,
+// the bearer token otherwise.
+func (m *defaultMiddleware) extractToken(r *http.Request) (string, bool, error) {
+	if m.config.DPoP != nil {
+		parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+		if strings.EqualFold(parts[0], "DPoP") {
+			rawToken, err := dpop.ExtractToken(r)
+			if err != nil {
+				return "", true, ErrMalformedAuthorization
+			}
+			return rawToken, true, nil
+		}
+	}
+	rawToken, err := ExtractToken(r, m.config.Extraction)
+	return rawToken, false, err
+}
+
+func (m *defaultMiddleware) dpopChallenge(err error) *Challenge {
+	challenge := m.reject(dpop.ErrorCode(err), err, DescriptionInvalidProof)
+	challenge.Scheme = "DPoP"
+	for _, algorithm := range m.config.DPoP.Algorithms() {
+		challenge.Algorithms = append(challenge.Algorithms, string(algorithm))
+	}
+	if m.config.DPoPNonce != nil {
+		challenge.Nonce = m.config.DPoPNonce()
+	}
+	return challenge
+}
+
+// reject logs the error and returns the challenge with the fixed description of the error.
+func (m *defaultMiddleware) reject(errorCode string, err error, fallback string) *Challenge {
+	if m.config.ErrorLog != nil {
+		m.config.ErrorLog.Printf("bearer: request rejected: %v", err)
+	} else {
+		log.Printf("bearer: request rejected: %v", err)
+	}
+	return m.challenge(errorCode, ErrorDescription(err, fallback))
+}
+
+func (m *defaultMiddleware) challenge(errorCode, errorDescription string) *Challenge {
+	return &Challenge{
+		ErrorCode:        errorCode,
+		ErrorDescription: errorDescription,
+		Realm:            m.config.Realm,
+	}
+}
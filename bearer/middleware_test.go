@@ -0,0 +1,250 @@
+package bearer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/radekg/app-kit-tokens/dpop"
+	"github.com/radekg/app-kit-tokens/jwks"
+	"github.com/radekg/app-kit-tokens/tokens"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func testJWKS(t *testing.T) (jwks.JWKS, jose.Signer) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: &privateKey.PublicKey, KeyID: "test", Algorithm: string(jose.RS256), Use: "sig"},
+	}}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(set)
+	}))
+	defer testServer.Close()
+	location, _ := url.Parse(testServer.URL)
+	keySet, err := jwks.ResolveJWKS(location, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: privateKey},
+		(&jose.SignerOptions{}).WithHeader("kid", "test").WithType("JWT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keySet, signer
+}
+
+func testToken(t *testing.T, signer jose.Signer, claims tokens.Claims) string {
+	rawToken, err := jwt.Signed(signer).Claims(map[string]interface{}(claims)).CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rawToken
+}
+
+func TestExtractToken(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/?access_token=query", nil)
+	request.Header.Set("Authorization", "bearer header")
+	if token, err := ExtractToken(request, nil); err != nil || token != "header" {
+		t.Fatalf("expected header token but received '%s', '%v'", token, err)
+	}
+	if _, err := ExtractToken(request, &Extraction{Query: true}); err != ErrMultipleTokens {
+		t.Fatalf("expected multiple tokens error but received '%v'", err)
+	}
+	request.Header.Del("Authorization")
+	if token, err := ExtractToken(request, &Extraction{Query: true}); err != nil || token != "query" {
+		t.Fatalf("expected query token but received '%s', '%v'", token, err)
+	}
+	if _, err := ExtractToken(request, nil); err != ErrTokenMissing {
+		t.Fatalf("expected token missing error but received '%v'", err)
+	}
+
+	request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("access_token=form"))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if token, err := ExtractToken(request, &Extraction{FormBody: true}); err != nil || token != "form" {
+		t.Fatalf("expected form body token but received '%s', '%v'", token, err)
+	}
+
+	request = httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	if _, err := ExtractToken(request, nil); err != ErrMalformedAuthorization {
+		t.Fatalf("expected malformed authorization error but received '%v'", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	keySet, signer := testJWKS(t)
+	logged := &bytes.Buffer{}
+	middleware := New(&Config{
+		ErrorLog:       log.New(logged, "", 0),
+		JWKS:           keySet,
+		Realm:          "api",
+		RequiredScopes: tokens.NewScopes("read"),
+		Validator:      tokens.DefaultClaimsValidator(&tokens.Expectations{Audiences: []string{"api"}}),
+	})
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accessToken, ok := AccessTokenFromContext(r.Context())
+		if !ok {
+			t.Fatal("expected access token in the request context")
+		}
+		if rawToken, ok := RawTokenFromContext(r.Context()); !ok || rawToken == "" {
+			t.Fatal("expected raw token in the request context")
+		}
+		sub, _ := accessToken.Sub()
+		w.Write([]byte(sub))
+	}))
+	expiry := float64(time.Now().Add(time.Hour).Unix())
+
+	serve := func(authorization string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		if authorization != "" {
+			request.Header.Set("Authorization", authorization)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	valid := testToken(t, signer, tokens.Claims{"sub": "subject", "aud": "api", "scope": "read write", "exp": expiry})
+	if response := serve("Bearer " + valid); response.Code != http.StatusOK || response.Body.String() != "subject" {
+		t.Fatalf("expected authenticated response but received '%d', '%s'", response.Code, response.Body.String())
+	}
+
+	response := serve("")
+	if response.Code != http.StatusUnauthorized || response.Header().Get("WWW-Authenticate") != `Bearer realm="api"` {
+		t.Fatalf("expected challenge without error but received '%d', '%s'", response.Code, response.Header().Get("WWW-Authenticate"))
+	}
+
+	response = serve("Bearer " + valid[:len(valid)-4] + "AAAA")
+	if response.Code != http.StatusUnauthorized ||
+		response.Header().Get("WWW-Authenticate") != `Bearer realm="api", error="invalid_token", error_description="token signature invalid"` {
+		t.Fatalf("expected invalid token challenge but received '%d', '%s'", response.Code, response.Header().Get("WWW-Authenticate"))
+	}
+	if !strings.HasPrefix(logged.String(), "bearer: request rejected: ") || strings.Contains(logged.String(), "token signature invalid") {
+		t.Fatalf("expected the detailed error to be logged but received '%s'", logged.String())
+	}
+
+	otherAudience := testToken(t, signer, tokens.Claims{"sub": "subject", "aud": "other", "scope": "read", "exp": expiry})
+	response = serve("Bearer " + otherAudience)
+	if !strings.Contains(response.Header().Get("WWW-Authenticate"), `error_description="token audience mismatch"`) {
+		t.Fatalf("expected audience mismatch challenge but received '%s'", response.Header().Get("WWW-Authenticate"))
+	}
+
+	noScope := testToken(t, signer, tokens.Claims{"sub": "subject", "aud": "api", "scope": "write", "exp": expiry})
+	response = serve("Bearer " + noScope)
+	if response.Code != http.StatusForbidden ||
+		!strings.Contains(response.Header().Get("WWW-Authenticate"), `error="insufficient_scope"`) ||
+		!strings.Contains(response.Header().Get("WWW-Authenticate"), `scope="read"`) {
+		t.Fatalf("expected insufficient scope challenge but received '%d', '%s'", response.Code, response.Header().Get("WWW-Authenticate"))
+	}
+
+	wildcardScope := testToken(t, signer, tokens.Claims{"sub": "subject", "aud": "api", "scope": "*", "exp": expiry})
+	if response := serve("Bearer " + wildcardScope); response.Code != http.StatusForbidden {
+		t.Fatalf("expected wildcard scope carried by the token not to satisfy the required scopes but received '%d'", response.Code)
+	}
+
+	if response := serve("Bearer a b"); response.Code != http.StatusBadRequest {
+		t.Fatalf("expected invalid request status but received '%d'", response.Code)
+	}
+}
+
+type testAuthorizer struct{}
+
+type testDenial struct{}
+
+func (d *testDenial) Error() string                 { return "tenant not allowed" }
+func (d *testDenial) RequiredScopes() tokens.Scopes { return tokens.NewScopes("admin") }
+
+func (a *testAuthorizer) Authorize(r *http.Request, accessToken tokens.AccessToken) error {
+	if tenant, _ := accessToken.RawClaims().GetClaimMustString("tenant"); tenant != "tenant-1" {
+		return &testDenial{}
+	}
+	return nil
+}
+
+func TestMiddlewareAuthorizer(t *testing.T) {
+	keySet, signer := testJWKS(t)
+	handler := New(&Config{JWKS: keySet, Authorizer: &testAuthorizer{}}).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	expiry := float64(time.Now().Add(time.Hour).Unix())
+
+	for tenant, expectedStatus := range map[string]int{"tenant-1": http.StatusOK, "tenant-2": http.StatusForbidden} {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("Authorization", "Bearer "+testToken(t, signer, tokens.Claims{"tenant": tenant, "exp": expiry}))
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != expectedStatus {
+			t.Fatalf("expected status '%d' for '%s' but received '%d'", expectedStatus, tenant, recorder.Code)
+		}
+		if expectedStatus == http.StatusForbidden &&
+			recorder.Header().Get("WWW-Authenticate") != `Bearer error="insufficient_scope", error_description="token does not permit the request", scope="admin"` {
+			t.Fatalf("expected insufficient scope challenge but received '%s'", recorder.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
+
+func TestMiddlewareDefaultValidator(t *testing.T) {
+	keySet, signer := testJWKS(t)
+	middleware := New(&Config{JWKS: keySet})
+	for name, claims := range map[string]tokens.Claims{
+		"expired":       {"sub": "subject", "exp": float64(time.Now().Add(-time.Minute).Unix())},
+		"not valid yet": {"sub": "subject", "exp": float64(time.Now().Add(time.Hour).Unix()), "nbf": float64(time.Now().Add(time.Minute).Unix())},
+		"without exp":   {"sub": "subject"},
+	} {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("Authorization", "Bearer "+testToken(t, signer, claims))
+		if _, _, challenge := middleware.Authenticate(request); challenge == nil || challenge.ErrorCode != ErrorCodeInvalidToken {
+			t.Fatalf("expected the invalid_token challenge for the %s token but received '%v'", name, challenge)
+		}
+	}
+}
+
+func TestMiddlewareDPoP(t *testing.T) {
+	keySet, signer := testJWKS(t)
+	proofer, _ := dpop.NewProofer(jose.ES256)
+	bound := testToken(t, signer, tokens.Claims{
+		"sub": "subject",
+		"exp": float64(time.Now().Add(time.Hour).Unix()),
+		"cnf": map[string]interface{}{"jkt": proofer.Thumbprint()},
+	})
+	serve := func(middleware Middleware, scheme string, withProof bool) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "http://api.example.com/orders", nil)
+		request.Header.Set("Authorization", scheme+" "+bound)
+		if withProof {
+			proof, _ := proofer.Proof(http.MethodGet, "http://api.example.com/orders", bound, "")
+			request.Header.Set("DPoP", proof)
+		}
+		recorder := httptest.NewRecorder()
+		middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	withoutDPoP := New(&Config{JWKS: keySet})
+	if response := serve(withoutDPoP, "Bearer", false); response.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the DPoP-bound token sent as bearer to be rejected but received '%d'", response.Code)
+	}
+	withDPoP := New(&Config{JWKS: keySet, DPoP: dpop.NewVerifier(nil), DPoPNonce: func() string { return "nonce" }})
+	if response := serve(withDPoP, "Bearer", true); response.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the DPoP-bound token sent as bearer to be rejected but received '%d'", response.Code)
+	}
+	if response := serve(withDPoP, "DPoP", true); response.Code != http.StatusOK {
+		t.Fatalf("expected the DPoP-bound token with the proof to be accepted but received '%d', '%s'",
+			response.Code, response.Header().Get("WWW-Authenticate"))
+	}
+	response := serve(withDPoP, "DPoP", false)
+	if response.Code != http.StatusUnauthorized ||
+		!strings.HasPrefix(response.Header().Get("WWW-Authenticate"), `DPoP error="invalid_dpop_proof"`) ||
+		response.Header().Get(dpop.NonceHeader) != "nonce" {
+		t.Fatalf("expected the DPoP challenge but received '%d', '%s'", response.Code, response.Header().Get("WWW-Authenticate"))
+	}
+}
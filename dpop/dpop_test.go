@@ -0,0 +1,167 @@
+package dpop
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/radekg/app-kit-tokens/tokens"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func TestProofVerification(t *testing.T) {
+	proofer, err := NewProofer(jose.ES256)
+	if err != nil {
+		t.Fatalf("expected proofer but received '%v'", err)
+	}
+	verifier := NewVerifier(nil)
+
+	proof, _ := proofer.Proof(http.MethodPost, "https://API.example.com:443/orders?page=2#top", "access-token", "")
+	verified, err := verifier.Verify(proof, http.MethodPost, "https://api.example.com/orders", "access-token")
+	if err != nil {
+		t.Fatalf("expected proof to verify but received '%v'", err)
+	}
+	if verified.Thumbprint != proofer.Thumbprint() {
+		t.Fatalf("expected the proofer thumbprint but received '%s'", verified.Thumbprint)
+	}
+	if _, err := verifier.Verify(proof, http.MethodPost, "https://api.example.com/orders", "access-token"); err != ErrProofReplayed {
+		t.Fatalf("expected ErrProofReplayed but received '%v'", err)
+	}
+
+	proof, _ = proofer.Proof(http.MethodPost, "https://api.example.com/orders", "access-token", "")
+	if _, err := verifier.Verify(proof, http.MethodGet, "https://api.example.com/orders", "access-token"); err != ErrMethodMismatch {
+		t.Fatalf("expected ErrMethodMismatch but received '%v'", err)
+	}
+	if _, err := verifier.Verify(proof, http.MethodPost, "https://api.example.com/payments", "access-token"); err != ErrURIMismatch {
+		t.Fatalf("expected ErrURIMismatch but received '%v'", err)
+	}
+	if _, err := verifier.Verify(proof, http.MethodPost, "https://api.example.com/orders", "other-token"); err != ErrAccessTokenHashMismatch {
+		t.Fatalf("expected ErrAccessTokenHashMismatch but received '%v'", err)
+	}
+
+	restricted := NewVerifier(&VerifierConfig{Algorithms: []jose.SignatureAlgorithm{jose.RS256}})
+	if _, err := restricted.Verify(proof, http.MethodPost, "https://api.example.com/orders", ""); err != ErrAlgorithmNotAllowed {
+		t.Fatalf("expected ErrAlgorithmNotAllowed but received '%v'", err)
+	}
+	future := NewVerifier(&VerifierConfig{Now: func() time.Time { return time.Now().Add(-time.Minute) }})
+	if _, err := future.Verify(proof, http.MethodPost, "https://api.example.com/orders", ""); err != ErrProofExpired {
+		t.Fatalf("expected ErrProofExpired but received '%v'", err)
+	}
+	nonced := NewVerifier(&VerifierConfig{Nonce: func(nonce string) bool { return nonce == "server-nonce" }})
+	if _, err := nonced.Verify(proof, http.MethodPost, "https://api.example.com/orders", ""); err != ErrUseNonce {
+		t.Fatalf("expected ErrUseNonce but received '%v'", err)
+	}
+	proof, _ = proofer.Proof(http.MethodPost, "https://api.example.com/orders", "", "server-nonce")
+	if _, err := nonced.Verify(proof, http.MethodPost, "https://api.example.com/orders", ""); err != nil {
+		t.Fatalf("expected proof with the nonce to verify but received '%v'", err)
+	}
+}
+
+func TestSelectAlgorithm(t *testing.T) {
+	if algorithm, _ := SelectAlgorithm([]string{"RS256", "PS256"}); algorithm != jose.PS256 {
+		t.Fatalf("expected the preferred supported algorithm but received '%s'", algorithm)
+	}
+	if _, err := SelectAlgorithm([]string{"HS256"}); err != ErrAlgorithmNotSupported {
+		t.Fatalf("expected ErrAlgorithmNotSupported but received '%v'", err)
+	}
+}
+
+func TestTransportAndRequestVerification(t *testing.T) {
+	proofer, _ := NewProofer(jose.EdDSA)
+	verifier := NewVerifier(&VerifierConfig{Nonce: func(nonce string) bool { return nonce == "server-nonce" }})
+	accessToken := tokens.DefaultAccessToken(tokens.Claims{
+		"cnf": map[string]interface{}{"jkt": proofer.Thumbprint()},
+	})
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		rawToken, err := ExtractToken(r)
+		if err != nil {
+			WriteChallenge(w, err, verifier.Algorithms(), "")
+			return
+		}
+		if _, err := verifier.VerifyRequest(r, rawToken, accessToken); err != nil {
+			WriteChallenge(w, err, verifier.Algorithms(), "server-nonce")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	source := tokens.TokenSourceFunc(func(ctx context.Context) (tokens.JWT, error) {
+		rawJWT, _ := json.Marshal(map[string]interface{}{"access_token": "access-token", "token_type": "DPoP"})
+		return tokens.DefaultJWT(rawJWT)
+	})
+	client := &http.Client{Transport: NewTransport(proofer, source, nil)}
+	resp, err := client.Post(server.URL+"/orders", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("expected response but received '%v'", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent || requests != 2 {
+		t.Fatalf("expected the request to be retried with the nonce but received '%d' after '%d' requests", resp.StatusCode, requests)
+	}
+
+	other, _ := NewProofer(jose.ES256)
+	client = &http.Client{Transport: NewTransport(other, source, nil)}
+	resp, _ = client.Get(server.URL + "/orders")
+	resp.Body.Close()
+	if !strings.Contains(resp.Header.Get("WWW-Authenticate"), `error="invalid_token"`) {
+		t.Fatalf("expected the invalid_token challenge for the unbound key but received '%s'", resp.Header.Get("WWW-Authenticate"))
+	}
+}
+
+type trackedBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *trackedBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestTransportClosesRequestBody(t *testing.T) {
+	proofer, _ := NewProofer(jose.EdDSA)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	newRequest := func() (*http.Request, *trackedBody) {
+		body := &trackedBody{Reader: strings.NewReader("{}")}
+		request, _ := http.NewRequest(http.MethodPost, server.URL, body)
+		request.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader("{}")), nil
+		}
+		return request, body
+	}
+
+	request, body := newRequest()
+	resp, err := NewTransport(proofer, nil, nil).RoundTrip(request)
+	if err != nil {
+		t.Fatalf("expected response but received '%v'", err)
+	}
+	resp.Body.Close()
+	if !body.closed {
+		t.Fatal("expected the request body to be closed once sent")
+	}
+
+	failing := tokens.TokenSourceFunc(func(ctx context.Context) (tokens.JWT, error) {
+		return nil, errors.New("token unavailable")
+	})
+	request, body = newRequest()
+	if _, err := NewTransport(proofer, failing, nil).RoundTrip(request); err == nil {
+		t.Fatal("expected the token source error")
+	}
+	if !body.closed {
+		t.Fatal("expected the request body to be closed on error")
+	}
+}
@@ -0,0 +1,168 @@
+package dpop
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/radekg/app-kit-tokens/signer"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+var (
+	// ErrAlgorithmNotSupported indicates a server not supporting any of the proof algorithms.
+	ErrAlgorithmNotSupported = errAlgorithmNotSupported()
+	// ErrInvalidTargetURI indicates a target URI which is not an absolute URI.
+	ErrInvalidTargetURI = errInvalidTargetURI()
+	// ErrPrivateKeyRequired indicates a proof key without the private key.
+	ErrPrivateKeyRequired = errPrivateKeyRequired()
+)
+
+func errAlgorithmNotSupported() error { return errors.New("no supported dpop signing algorithm") }
+func errInvalidTargetURI() error      { return errors.New("dpop target uri must be absolute") }
+func errPrivateKeyRequired() error    { return errors.New("dpop proof key must be a private key") }
+
+// ProofType is the typ header of DPoP proofs.
+const ProofType = "dpop+jwt"
+
+// DefaultAlgorithms lists the asymmetric algorithms accepted for proofs, in the order of preference.
+var DefaultAlgorithms = []jose.SignatureAlgorithm{
+	jose.ES256, jose.EdDSA, jose.ES384, jose.ES512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.RS256, jose.RS384, jose.RS512,
+}
+
+// SelectAlgorithm returns the most preferred of the DefaultAlgorithms the server supports.
+// Use the dpop_signing_alg_values_supported discovery value, ES256 is returned when the list is empty.
+func SelectAlgorithm(supported []string) (jose.SignatureAlgorithm, error) {
+	if len(supported) == 0 {
+		return jose.ES256, nil
+	}
+	for _, algorithm := range DefaultAlgorithms {
+		for _, value := range supported {
+			if value == string(algorithm) {
+				return algorithm, nil
+			}
+		}
+	}
+	return "", ErrAlgorithmNotSupported
+}
+
+// Proofer creates DPoP proofs.
+type Proofer interface {
+	// Proof returns the proof for a request with the method to the target URI.
+	// The ath claim is included when the access token is not empty,
+	// the nonce claim is included when the server provided nonce is not empty.
+	Proof(method, targetURI, accessToken, nonce string) (string, error)
+	// PublicKey returns the public key embedded in the proofs.
+	PublicKey() jose.JSONWebKey
+	// Thumbprint returns the RFC 7638 thumbprint of the public key, the cnf.jkt claim of bound tokens.
+	Thumbprint() string
+}
+
+type defaultProofer struct {
+	key        jose.JSONWebKey
+	signer     jose.Signer
+	thumbprint string
+}
+
+// NewProofer returns a proofer with a freshly generated ephemeral key.
+func NewProofer(algorithm jose.SignatureAlgorithm) (Proofer, error) {
+	key, err := signer.GenerateKey(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return NewProoferWithKey(key)
+}
+
+// NewProoferWithKey returns a proofer signing with the private key.
+// The key algorithm must be set.
+func NewProoferWithKey(key jose.JSONWebKey) (Proofer, error) {
+	if key.IsPublic() {
+		return nil, ErrPrivateKeyRequired
+	}
+	publicKey := key.Public()
+	value, err := publicKey.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	options := (&jose.SignerOptions{EmbedJWK: true}).WithType(ProofType)
+	proofSigner, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.SignatureAlgorithm(key.Algorithm),
+		Key:       key,
+	}, options)
+	if err != nil {
+		return nil, err
+	}
+	return &defaultProofer{
+		key:        publicKey,
+		signer:     proofSigner,
+		thumbprint: base64.RawURLEncoding.EncodeToString(value),
+	}, nil
+}
+
+func (p *defaultProofer) Proof(method, targetURI, accessToken, nonce string) (string, error) {
+	htu, err := normalizeURI(targetURI)
+	if err != nil {
+		return "", err
+	}
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+	claims := map[string]interface{}{
+		"htm": method,
+		"htu": htu,
+		"iat": time.Now().Unix(),
+		"jti": base64.RawURLEncoding.EncodeToString(jti),
+	}
+	if accessToken != "" {
+		claims["ath"] = AccessTokenHash(accessToken)
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	return jwt.Signed(p.signer).Claims(claims).CompactSerialize()
+}
+
+func (p *defaultProofer) PublicKey() jose.JSONWebKey {
+	return p.key
+}
+
+func (p *defaultProofer) Thumbprint() string {
+	return p.thumbprint
+}
+
+// AccessTokenHash returns the ath claim value for the access token.
+func AccessTokenHash(accessToken string) string {
+	hash := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// normalizeURI returns the htu value of the URI: the URI without the query and fragment,
+// with the lower case scheme and host and without the default port.
+func normalizeURI(value string) (string, error) {
+	u, err := url.Parse(value)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", ErrInvalidTargetURI
+	}
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Host)
+	if (scheme == "https" && strings.HasSuffix(host, ":443")) || (scheme == "http" && strings.HasSuffix(host, ":80")) {
+		host = host[:strings.LastIndex(host, ":")]
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	return scheme + "://" + host + path, nil
+}
@@ -0,0 +1,44 @@
+package dpop
+
+import (
+	"sync"
+	"time"
+)
+
+// ReplayCache records the jti of accepted proofs.
+// Share the cache between the instances of a service with an implementation backed by shared storage.
+type ReplayCache interface {
+	// Seen records the jti until it expires, returns true if the jti was already recorded.
+	Seen(jti string, expiresAt time.Time) bool
+}
+
+type memoryReplayCache struct {
+	sync.Mutex
+	entries   map[string]time.Time
+	nextPrune time.Time
+	now       func() time.Time
+}
+
+// MemoryReplayCache returns an in-memory replay cache.
+func MemoryReplayCache() ReplayCache {
+	return &memoryReplayCache{entries: map[string]time.Time{}, now: time.Now}
+}
+
+func (c *memoryReplayCache) Seen(jti string, expiresAt time.Time) bool {
+	c.Lock()
+	defer c.Unlock()
+	now := c.now()
+	if now.After(c.nextPrune) {
+		for key, entryExpiresAt := range c.entries {
+			if now.After(entryExpiresAt) {
+				delete(c.entries, key)
+			}
+		}
+		c.nextPrune = now.Add(time.Minute)
+	}
+	if entryExpiresAt, ok := c.entries[jti]; ok && !now.After(entryExpiresAt) {
+		return true
+	}
+	c.entries[jti] = expiresAt
+	return false
+}
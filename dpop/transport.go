@@ -0,0 +1,114 @@
+package dpop
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/radekg/app-kit-tokens/tokens"
+)
+
+// NonceHeader is the header carrying the server provided nonce.
+const NonceHeader = "DPoP-Nonce"
+
+type transport struct {
+	base    http.RoundTripper
+	nonces  sync.Map
+	proofer Proofer
+	source  tokens.TokenSource
+}
+
+// NewTransport returns a transport attaching the DPoP proof to outgoing requests.
+// With the token source, the access token is sent with the DPoP authorization scheme and
+// the proof carries its hash. Tokens of the Bearer type were not bound by the server and are sent
+// with the Bearer scheme. Without the source only the proof is attached, use this for the
+// token endpoint requests.
+// Nonces provided in the DPoP-Nonce response header are remembered per origin, a request answered
+// with 400 or 401 and a new nonce is retried once with the new nonce if the request body can be replayed.
+// The base defaults to http.DefaultTransport.
+func NewTransport(proofer Proofer, source tokens.TokenSource, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base, proofer: proofer, source: source}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// a RoundTripper must close the request body, even on errors.
+	// The outgoing requests carry copies of a replayable body, the original is closed once sent:
+	if hasReplayableBody(req) {
+		defer req.Body.Close()
+	}
+	accessToken, scheme := "", ""
+	if t.source != nil {
+		token, err := t.source.Token(req.Context())
+		if err != nil {
+			closeUnsentBody(req)
+			return nil, err
+		}
+		accessToken, scheme = token.AccessToken(), "DPoP"
+		if strings.EqualFold(token.TokenType(), "Bearer") {
+			scheme = "Bearer"
+		}
+	}
+	origin := req.URL.Scheme + "://" + req.URL.Host
+	nonce := ""
+	if value, ok := t.nonces.Load(origin); ok {
+		nonce = value.(string)
+	}
+
+	resp, err := t.roundTrip(req, accessToken, scheme, nonce)
+	if err != nil {
+		return nil, err
+	}
+	newNonce := resp.Header.Get(NonceHeader)
+	if newNonce == "" || newNonce == nonce {
+		return resp, nil
+	}
+	t.nonces.Store(origin, newNonce)
+	if resp.StatusCode != http.StatusBadRequest && resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+	if req.Body != nil && req.Body != http.NoBody && !hasReplayableBody(req) {
+		return resp, nil
+	}
+	resp.Body.Close()
+	return t.roundTrip(req, accessToken, scheme, newNonce)
+}
+
+func (t *transport) roundTrip(req *http.Request, accessToken, scheme, nonce string) (*http.Response, error) {
+	proofToken := accessToken
+	if scheme == "Bearer" {
+		proofToken = ""
+	}
+	proof, err := t.proofer.Proof(req.Method, req.URL.String(), proofToken, nonce)
+	if err != nil {
+		closeUnsentBody(req)
+		return nil, err
+	}
+	// a RoundTripper must not modify the request:
+	outgoing := req.Clone(req.Context())
+	if hasReplayableBody(req) {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		outgoing.Body = body
+	}
+	outgoing.Header.Set("DPoP", proof)
+	if accessToken != "" {
+		outgoing.Header.Set("Authorization", scheme+" "+accessToken)
+	}
+	return t.base.RoundTrip(outgoing)
+}
+
+func hasReplayableBody(req *http.Request) bool {
+	return req.GetBody != nil && req.Body != nil && req.Body != http.NoBody
+}
+
+// closeUnsentBody closes a body which is not replayable, the base transport closes it otherwise.
+func closeUnsentBody(req *http.Request) {
+	if !hasReplayableBody(req) && req.Body != nil {
+		req.Body.Close()
+	}
+}
@@ -0,0 +1,294 @@
+package dpop
+
+import (
+	"crypto"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/radekg/app-kit-tokens/tokens"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+var (
+	// ErrAccessTokenHashMismatch indicates a proof whose ath claim does not match the access token.
+	ErrAccessTokenHashMismatch = errAccessTokenHashMismatch()
+	// ErrAlgorithmNotAllowed indicates a proof signed with an algorithm not allowed by the verifier.
+	ErrAlgorithmNotAllowed = errAlgorithmNotAllowed()
+	// ErrInvalidProof indicates a malformed proof or a proof with an invalid signature.
+	ErrInvalidProof = errInvalidProof()
+	// ErrKeyBindingMismatch indicates an access token not bound to the proof key.
+	ErrKeyBindingMismatch = errKeyBindingMismatch()
+	// ErrMethodMismatch indicates a proof issued for a different HTTP method.
+	ErrMethodMismatch = errMethodMismatch()
+	// ErrMultipleProofs indicates a request with more than one DPoP header.
+	ErrMultipleProofs = errMultipleProofs()
+	// ErrProofExpired indicates a proof issued outside of the accepted time window.
+	ErrProofExpired = errProofExpired()
+	// ErrProofMissing indicates a request without the DPoP header.
+	ErrProofMissing = errProofMissing()
+	// ErrProofReplayed indicates a proof whose jti was already used.
+	ErrProofReplayed = errProofReplayed()
+	// ErrTokenMissing indicates a request without the DPoP authorization.
+	ErrTokenMissing = errTokenMissing()
+	// ErrURIMismatch indicates a proof issued for a different target URI.
+	ErrURIMismatch = errURIMismatch()
+	// ErrUseNonce indicates a proof without the valid server provided nonce.
+	ErrUseNonce = errUseNonce()
+)
+
+func errAccessTokenHashMismatch() error { return errors.New("dpop proof ath mismatch") }
+func errAlgorithmNotAllowed() error     { return errors.New("dpop proof algorithm not allowed") }
+func errInvalidProof() error            { return errors.New("invalid dpop proof") }
+func errKeyBindingMismatch() error      { return errors.New("access token not bound to the dpop key") }
+func errMethodMismatch() error          { return errors.New("dpop proof htm mismatch") }
+func errMultipleProofs() error          { return errors.New("multiple dpop proofs") }
+func errProofExpired() error            { return errors.New("dpop proof iat outside of the accepted window") }
+func errProofMissing() error            { return errors.New("dpop proof missing") }
+func errProofReplayed() error           { return errors.New("dpop proof replayed") }
+func errTokenMissing() error            { return errors.New("dpop token missing") }
+func errURIMismatch() error             { return errors.New("dpop proof htu mismatch") }
+func errUseNonce() error                { return errors.New("dpop proof nonce required") }
+
+// Error codes of the DPoP WWW-Authenticate challenge:
+const (
+	// ErrorCodeInvalidProof indicates an invalid proof.
+	ErrorCodeInvalidProof = "invalid_dpop_proof"
+	// ErrorCodeInvalidToken indicates an access token not bound to the proof key.
+	ErrorCodeInvalidToken = "invalid_token"
+	// ErrorCodeUseNonce asks the client to repeat the request with the nonce from the DPoP-Nonce header.
+	ErrorCodeUseNonce = "use_dpop_nonce"
+)
+
+// Proof represents a verified proof.
+type Proof struct {
+	IssuedAt   time.Time
+	JTI        string
+	Key        jose.JSONWebKey
+	Nonce      string
+	Thumbprint string
+}
+
+// VerifierConfig represents the verifier configuration.
+type VerifierConfig struct {
+	// Algorithms: the accepted proof algorithms, defaults to DefaultAlgorithms.
+	// Advertise them as dpop_signing_alg_values_supported.
+	Algorithms []jose.SignatureAlgorithm
+	// Leeway: accepted clock skew of proofs issued in the future, defaults to 5 seconds.
+	Leeway time.Duration
+	// MaxAge: the maximum age of a proof, defaults to 1 minute.
+	MaxAge time.Duration
+	// Nonce: validates the server provided nonce, optional. Proofs do not need a nonce when nil.
+	Nonce func(nonce string) bool
+	// Now: the clock, defaults to time.Now.
+	Now func() time.Time
+	// ReplayCache: records the jti of accepted proofs, defaults to MemoryReplayCache.
+	ReplayCache ReplayCache
+	// TargetURI: returns the URI the request was sent to, optional.
+	// Defaults to the request host and path with the https scheme for TLS connections.
+	// Set this when the server runs behind a proxy.
+	TargetURI func(r *http.Request) string
+}
+
+// Verifier verifies DPoP proofs.
+type Verifier interface {
+	// Algorithms returns the accepted proof algorithms.
+	Algorithms() []jose.SignatureAlgorithm
+	// Verify verifies the proof of a request with the method to the target URI.
+	// The ath claim is verified when the access token is not empty.
+	Verify(proof, method, targetURI, accessToken string) (*Proof, error)
+	// VerifyRequest verifies the DPoP header proof of a request authorized with the access token
+	// and checks the access token cnf.jkt claim binds the token to the proof key.
+	VerifyRequest(r *http.Request, rawToken string, accessToken tokens.AccessToken) (*Proof, error)
+}
+
+type defaultVerifier struct {
+	config *VerifierConfig
+}
+
+// NewVerifier returns a verifier for the configuration.
+func NewVerifier(config *VerifierConfig) Verifier {
+	if config == nil {
+		config = &VerifierConfig{}
+	}
+	if len(config.Algorithms) == 0 {
+		config.Algorithms = DefaultAlgorithms
+	}
+	if config.Leeway == 0 {
+		config.Leeway = 5 * time.Second
+	}
+	if config.MaxAge == 0 {
+		config.MaxAge = time.Minute
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	if config.ReplayCache == nil {
+		config.ReplayCache = MemoryReplayCache()
+	}
+	if config.TargetURI == nil {
+		config.TargetURI = requestURI
+	}
+	return &defaultVerifier{config: config}
+}
+
+func (v *defaultVerifier) Algorithms() []jose.SignatureAlgorithm {
+	return v.config.Algorithms
+}
+
+func (v *defaultVerifier) Verify(proof, method, targetURI, accessToken string) (*Proof, error) {
+	token, err := jwt.ParseSigned(proof)
+	if err != nil || len(token.Headers) != 1 {
+		return nil, ErrInvalidProof
+	}
+	header := token.Headers[0]
+	if typ, _ := header.ExtraHeaders[jose.HeaderType].(string); typ != ProofType {
+		return nil, ErrInvalidProof
+	}
+	if !v.allowed(jose.SignatureAlgorithm(header.Algorithm)) {
+		return nil, ErrAlgorithmNotAllowed
+	}
+	if header.JSONWebKey == nil || !header.JSONWebKey.IsPublic() || !header.JSONWebKey.Valid() {
+		return nil, ErrInvalidProof
+	}
+	claims := map[string]interface{}{}
+	if err := token.Claims(header.JSONWebKey, &claims); err != nil {
+		return nil, ErrInvalidProof
+	}
+	proofClaims := tokens.Claims(claims)
+	jti, _ := proofClaims.GetClaimMustString("jti")
+	htm, _ := proofClaims.GetClaimMustString("htm")
+	htu, _ := proofClaims.GetClaimMustString("htu")
+	iat, ok := proofClaims["iat"].(float64)
+	if jti == "" || htm == "" || htu == "" || !ok {
+		return nil, ErrInvalidProof
+	}
+
+	if htm != method {
+		return nil, ErrMethodMismatch
+	}
+	expectedURI, err := normalizeURI(targetURI)
+	if err != nil {
+		return nil, err
+	}
+	if proofURI, err := normalizeURI(htu); err != nil || proofURI != expectedURI {
+		return nil, ErrURIMismatch
+	}
+	nonce, _ := proofClaims.GetClaimMustString("nonce")
+	if v.config.Nonce != nil && (nonce == "" || !v.config.Nonce(nonce)) {
+		return nil, ErrUseNonce
+	}
+	now := v.config.Now()
+	issuedAt := time.Unix(int64(iat), 0)
+	if issuedAt.Before(now.Add(-v.config.MaxAge)) || issuedAt.After(now.Add(v.config.Leeway)) {
+		return nil, ErrProofExpired
+	}
+	if accessToken != "" {
+		if ath, _ := proofClaims.GetClaimMustString("ath"); ath != AccessTokenHash(accessToken) {
+			return nil, ErrAccessTokenHashMismatch
+		}
+	}
+	thumbprint, err := header.JSONWebKey.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, ErrInvalidProof
+	}
+	// the jti is checked last so rejected proofs do not use up the jti:
+	if v.config.ReplayCache.Seen(jti, issuedAt.Add(v.config.MaxAge+v.config.Leeway)) {
+		return nil, ErrProofReplayed
+	}
+	return &Proof{
+		IssuedAt:   issuedAt,
+		JTI:        jti,
+		Key:        *header.JSONWebKey,
+		Nonce:      nonce,
+		Thumbprint: base64.RawURLEncoding.EncodeToString(thumbprint),
+	}, nil
+}
+
+func (v *defaultVerifier) VerifyRequest(r *http.Request, rawToken string, accessToken tokens.AccessToken) (*Proof, error) {
+	proofs := r.Header.Values("DPoP")
+	switch len(proofs) {
+	case 0:
+		return nil, ErrProofMissing
+	case 1:
+	default:
+		return nil, ErrMultipleProofs
+	}
+	proof, err := v.Verify(proofs[0], r.Method, v.config.TargetURI(r), rawToken)
+	if err != nil {
+		return nil, err
+	}
+	cnf, _ := accessToken.Cnf()
+	if jkt, _ := cnf.GetClaimMustString("jkt"); jkt == "" || jkt != proof.Thumbprint {
+		return nil, ErrKeyBindingMismatch
+	}
+	return proof, nil
+}
+
+func (v *defaultVerifier) allowed(algorithm jose.SignatureAlgorithm) bool {
+	for _, allowed := range v.config.Algorithms {
+		if allowed == algorithm {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractToken extracts the access token sent with the DPoP authorization scheme.
+func ExtractToken(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return "", ErrTokenMissing
+	}
+	parts := strings.SplitN(header, " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "DPoP") {
+		return "", ErrTokenMissing
+	}
+	token := strings.TrimSpace(parts[1])
+	if token == "" || strings.ContainsAny(token, " \t") {
+		return "", ErrTokenMissing
+	}
+	return token, nil
+}
+
+// ErrorCode returns the challenge error code for a verification error.
+func ErrorCode(err error) string {
+	switch err {
+	case ErrUseNonce:
+		return ErrorCodeUseNonce
+	case ErrKeyBindingMismatch, ErrTokenMissing:
+		return ErrorCodeInvalidToken
+	default:
+		return ErrorCodeInvalidProof
+	}
+}
+
+// WriteChallenge writes the DPoP challenge response for a verification error,
+// advertising the accepted algorithms. The nonce is sent in the DPoP-Nonce header when not empty.
+func WriteChallenge(w http.ResponseWriter, err error, algorithms []jose.SignatureAlgorithm, nonce string) {
+	values := make([]string, 0, len(algorithms))
+	for _, algorithm := range algorithms {
+		values = append(values, string(algorithm))
+	}
+	challenge := `DPoP error="` + ErrorCode(err) + `"`
+	if len(values) > 0 {
+		challenge = challenge + `, algs="` + strings.Join(values, " ") + `"`
+	}
+	if nonce != "" {
+		w.Header().Set(NonceHeader, nonce)
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	w.WriteHeader(http.StatusUnauthorized)
+}
+
+// requestURI returns the URI of a request received directly from the client.
+func requestURI(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.EscapedPath()
+}
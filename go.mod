@@ -6,4 +6,5 @@ require (
 	github.com/square/go-jose v2.5.1+incompatible
 	golang.org/x/crypto v0.0.0-20201124201722-c8d3bf9c5392 // indirect
 	gopkg.in/square/go-jose.v2 v2.5.1
+	gopkg.in/yaml.v2 v2.4.0
 )
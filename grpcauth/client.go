@@ -0,0 +1,33 @@
+package grpcauth
+
+import (
+	"context"
+
+	"github.com/radekg/app-kit-tokens/tokens"
+	"google.golang.org/grpc/credentials"
+)
+
+type perRPCCredentials struct {
+	requireTLS bool
+	source     tokens.TokenSource
+}
+
+// PerRPCCredentials returns call credentials attaching the access token from the source
+// as the authorization metadata. Wrap the source with tokens.ReuseTokenSource to refresh
+// the token before it expires instead of obtaining a new token for every call.
+// Tokens should only be sent over TLS, disable requireTLS for local development only.
+func PerRPCCredentials(source tokens.TokenSource, requireTLS bool) credentials.PerRPCCredentials {
+	return &perRPCCredentials{requireTLS: requireTLS, source: source}
+}
+
+func (c *perRPCCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	token, err := c.source.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"authorization": "Bearer " + token.AccessToken()}, nil
+}
+
+func (c *perRPCCredentials) RequireTransportSecurity() bool {
+	return c.requireTLS
+}
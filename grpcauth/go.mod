@@ -0,0 +1,10 @@
+module github.com/radekg/app-kit-tokens/grpcauth
+
+go 1.14
+
+require (
+	github.com/radekg/app-kit-tokens v0.0.0-20261019060152-0239f1601e1a
+	google.golang.org/genproto v0.0.0-20200526211855-cb27e3aa2013
+	google.golang.org/grpc v1.34.0
+	gopkg.in/square/go-jose.v2 v2.5.1
+)
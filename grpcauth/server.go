@@ -0,0 +1,233 @@
+package grpcauth
+
+import (
+	"context"
+	"crypto/x509"
+	"log"
+	"strings"
+
+	"github.com/radekg/app-kit-tokens/bearer"
+	"github.com/radekg/app-kit-tokens/dpop"
+	"github.com/radekg/app-kit-tokens/jwks"
+	"github.com/radekg/app-kit-tokens/mtls"
+	"github.com/radekg/app-kit-tokens/tokens"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain is the domain of the error details attached to the returned statuses.
+const ErrorDomain = "app-kit-tokens"
+
+// Authorizer authorizes authenticated calls.
+type Authorizer interface {
+	// Authorize returns an error if the access token does not permit the call of the full method.
+	Authorize(ctx context.Context, fullMethod string, accessToken tokens.AccessToken) error
+}
+
+// Config represents the interceptors configuration.
+type Config struct {
+	// Authorizer: authorizes the call after authentication, optional.
+	// Calls not authorized fail with codes.PermissionDenied.
+	Authorizer Authorizer
+	// CertificateBinding: verifies RFC 8705 certificate-bound tokens against the client certificate
+	// of the TLS connection, optional. The ClientCertificate function of the binding is not used.
+	CertificateBinding *mtls.Binding
+	// DPoP: accepts tokens sent with the DPoP scheme and verifies the proof sent in the dpop metadata,
+	// optional. The proof is issued for the POST method and the target URI of the call.
+	// Tokens bound to a DPoP key with the cnf jkt claim are rejected when sent with the Bearer scheme,
+	// whether this is set or not.
+	DPoP dpop.Verifier
+	// DPoPTargetURI: returns the target URI of the call, optional. Defaults to the URI built from
+	// the transport security, the :authority metadata and the full method.
+	DPoPTargetURI func(ctx context.Context, fullMethod string) string
+	// ErrorLog: logs the errors of rejected calls, the statuses carry fixed descriptions only,
+	// see bearer.ErrorDescription. Defaults to the standard logger of the log package.
+	ErrorLog *log.Logger
+	// Exempt: returns true for full methods not requiring a token, optional.
+	Exempt func(fullMethod string) bool
+	// JWKS: verifies the token signature.
+	JWKS jwks.JWKS
+	// Validator: validates the token claims, defaults to tokens.DefaultClaimsValidator(nil)
+	// which rejects tokens without exp, expired tokens and tokens not valid yet.
+	Validator tokens.ClaimsValidator
+}
+
+// UnaryServerInterceptor returns a unary server interceptor verifying the authorization metadata.
+// The access token is available in the handler context through bearer.AccessTokenFromContext.
+func UnaryServerInterceptor(config *Config) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		newCtx, err := authenticate(ctx, info.FullMethod, config)
+		if err != nil {
+			return nil, err
+		}
+		return handler(newCtx, req)
+	}
+}
+
+// StreamServerInterceptor returns a stream server interceptor verifying the authorization metadata.
+// The access token is available in the stream context through bearer.AccessTokenFromContext.
+func StreamServerInterceptor(config *Config) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		newCtx, err := authenticate(ss.Context(), info.FullMethod, config)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedServerStream{ServerStream: ss, ctx: newCtx})
+	}
+}
+
+type authenticatedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func authenticate(ctx context.Context, fullMethod string, config *Config) (context.Context, error) {
+	if config.Exempt != nil && config.Exempt(fullMethod) {
+		return ctx, nil
+	}
+	rawToken, usesDPoP, err := tokenFromMetadata(ctx, config.DPoP != nil)
+	if err != nil {
+		return nil, reject(config, codes.Unauthenticated, bearer.ErrorCodeInvalidRequest, err, bearer.ErrMalformedAuthorization.Error())
+	}
+	read := config.JWKS.ReadSigned(rawToken)
+	if read.Error() != nil {
+		return nil, reject(config, codes.Unauthenticated, bearer.ErrorCodeInvalidToken, read.Error(), bearer.DescriptionInvalidSignature)
+	}
+	validator := config.Validator
+	if validator == nil {
+		validator = tokens.DefaultClaimsValidator(nil)
+	}
+	if err := validator.Validate(read.Claims()); err != nil {
+		return nil, reject(config, codes.Unauthenticated, bearer.ErrorCodeInvalidToken, err, bearer.DescriptionInvalidClaims)
+	}
+	accessToken := tokens.DefaultAccessToken(read.Claims())
+	if usesDPoP {
+		if err := verifyDPoP(ctx, fullMethod, config, rawToken, accessToken); err != nil {
+			return nil, reject(config, codes.Unauthenticated, dpop.ErrorCode(err), err, bearer.DescriptionInvalidProof)
+		}
+	} else if cnf, _ := accessToken.Cnf(); cnf.HasClaim("jkt") {
+		// a DPoP-bound token without the proof is a replayed token:
+		return nil, newStatus(codes.Unauthenticated, bearer.ErrorCodeInvalidToken, "token is bound to a DPoP key")
+	}
+	if config.CertificateBinding != nil {
+		if err := config.CertificateBinding.VerifyCertificate(peerCertificate(ctx), accessToken); err != nil {
+			return nil, reject(config, codes.Unauthenticated, bearer.ErrorCodeInvalidToken, err, bearer.DescriptionInvalidBinding)
+		}
+	}
+	if config.Authorizer != nil {
+		if err := config.Authorizer.Authorize(ctx, fullMethod, accessToken); err != nil {
+			return nil, reject(config, codes.PermissionDenied, bearer.ErrorCodeInsufficientScope, err, bearer.DescriptionNotAuthorized)
+		}
+	}
+	return bearer.WithAccessToken(ctx, accessToken, rawToken), nil
+}
+
+// peerCertificate returns the leaf client certificate of the TLS connection, nil if there is none.
+func peerCertificate(ctx context.Context) *x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return nil
+	}
+	return tlsInfo.State.PeerCertificates[0]
+}
+
+// tokenFromMetadata returns the token of the authorization metadata and whether it uses the DPoP scheme.
+func tokenFromMetadata(ctx context.Context, acceptDPoP bool) (string, bool, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false, bearer.ErrTokenMissing
+	}
+	values := md.Get("authorization")
+	switch len(values) {
+	case 0:
+		return "", false, bearer.ErrTokenMissing
+	case 1:
+	default:
+		return "", false, bearer.ErrMultipleTokens
+	}
+	parts := strings.SplitN(values[0], " ", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+		return "", false, bearer.ErrMalformedAuthorization
+	}
+	switch {
+	case strings.EqualFold(parts[0], "Bearer"):
+		return strings.TrimSpace(parts[1]), false, nil
+	case acceptDPoP && strings.EqualFold(parts[0], "DPoP"):
+		return strings.TrimSpace(parts[1]), true, nil
+	default:
+		return "", false, bearer.ErrMalformedAuthorization
+	}
+}
+
+// verifyDPoP verifies the proof of the dpop metadata is bound to the access token key.
+func verifyDPoP(ctx context.Context, fullMethod string, config *Config, rawToken string, accessToken tokens.AccessToken) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	proofs := md.Get("dpop")
+	switch len(proofs) {
+	case 0:
+		return dpop.ErrProofMissing
+	case 1:
+	default:
+		return dpop.ErrMultipleProofs
+	}
+	targetURI := config.DPoPTargetURI
+	if targetURI == nil {
+		targetURI = callURI
+	}
+	proof, err := config.DPoP.Verify(proofs[0], "POST", targetURI(ctx, fullMethod), rawToken)
+	if err != nil {
+		return err
+	}
+	cnf, _ := accessToken.Cnf()
+	if jkt, _ := cnf.GetClaimMustString("jkt"); jkt == "" || jkt != proof.Thumbprint {
+		return dpop.ErrKeyBindingMismatch
+	}
+	return nil
+}
+
+// callURI returns the URI of the call, gRPC calls are HTTP/2 POST requests to the full method path.
+func callURI(ctx context.Context, fullMethod string) string {
+	scheme := "http"
+	if p, ok := peer.FromContext(ctx); ok {
+		if _, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			scheme = "https"
+		}
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	authority := ""
+	if values := md.Get(":authority"); len(values) > 0 {
+		authority = values[0]
+	}
+	return scheme + "://" + authority + fullMethod
+}
+
+// reject logs the error and returns the status with the fixed description of the error.
+func reject(config *Config, code codes.Code, reason string, err error, fallback string) error {
+	if config.ErrorLog != nil {
+		config.ErrorLog.Printf("grpcauth: call rejected: %v", err)
+	} else {
+		log.Printf("grpcauth: call rejected: %v", err)
+	}
+	return newStatus(code, reason, bearer.ErrorDescription(err, fallback))
+}
+
+func newStatus(code codes.Code, reason, description string) error {
+	st := status.New(code, description)
+	if withDetails, err := st.WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: ErrorDomain}); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
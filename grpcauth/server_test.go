@@ -0,0 +1,189 @@
+package grpcauth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/radekg/app-kit-tokens/bearer"
+	"github.com/radekg/app-kit-tokens/dpop"
+	"github.com/radekg/app-kit-tokens/mtls"
+	"github.com/radekg/app-kit-tokens/tokens"
+	"github.com/radekg/app-kit-tokens/tokenstest"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"gopkg.in/square/go-jose.v2"
+)
+
+type testAuthorizer struct{}
+
+func (a *testAuthorizer) Authorize(ctx context.Context, fullMethod string, accessToken tokens.AccessToken) error {
+	if scopes, _ := accessToken.Scopes(); !scopes.Has("write") && fullMethod == "/test.Service/Write" {
+		return errors.New("missing scope write")
+	}
+	return nil
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestInterceptors(t *testing.T) {
+	factory := tokenstest.MustNew(t, nil)
+	rawToken := factory.MustSign(t, tokenstest.KeycloakAccessToken, tokenstest.WithClaims(tokens.Claims{
+		"sub":   "subject",
+		"scope": "read",
+	}))
+	config := &Config{
+		Authorizer: &testAuthorizer{},
+		Exempt:     func(fullMethod string) bool { return fullMethod == "/grpc.health.v1.Health/Check" },
+		JWKS:       factory.JWKS(),
+	}
+	unary := UnaryServerInterceptor(config)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		accessToken, ok := bearer.AccessTokenFromContext(ctx)
+		if !ok {
+			return nil, nil
+		}
+		sub, _ := accessToken.Sub()
+		return sub, nil
+	}
+	call := func(ctx context.Context, fullMethod string) (interface{}, error) {
+		return unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: fullMethod}, handler)
+	}
+	authorized := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+rawToken))
+
+	if sub, err := call(authorized, "/test.Service/Read"); err != nil || sub != "subject" {
+		t.Fatalf("expected authenticated call but received '%v', '%v'", sub, err)
+	}
+	if _, err := call(context.Background(), "/grpc.health.v1.Health/Check"); err != nil {
+		t.Fatalf("expected exempt call but received '%v'", err)
+	}
+
+	_, err := call(context.Background(), "/test.Service/Read")
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected unauthenticated status but received '%v'", err)
+	}
+	details := status.Convert(err).Details()
+	if len(details) != 1 || details[0].(*errdetails.ErrorInfo).Reason != bearer.ErrorCodeInvalidRequest {
+		t.Fatalf("expected invalid request error details but received '%v'", details)
+	}
+
+	tampered := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+rawToken+"x"))
+	if _, err := call(tampered, "/test.Service/Read"); status.Code(err) != codes.Unauthenticated ||
+		status.Convert(err).Message() != bearer.DescriptionInvalidSignature {
+		t.Fatalf("expected unauthenticated status with a fixed description but received '%v'", err)
+	}
+	expired := factory.MustSign(t, tokenstest.KeycloakAccessToken, tokenstest.ExpiresIn(-time.Minute))
+	if _, err := call(metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+expired)), "/test.Service/Read"); status.Code(err) != codes.Unauthenticated ||
+		status.Convert(err).Message() != tokens.ErrTokenExpired.Error() {
+		t.Fatalf("expected unauthenticated status for the expired token but received '%v'", err)
+	}
+	if _, err := call(authorized, "/test.Service/Write"); status.Code(err) != codes.PermissionDenied ||
+		status.Convert(err).Message() != bearer.DescriptionNotAuthorized {
+		t.Fatalf("expected permission denied status without the authorizer details but received '%v'", err)
+	}
+
+	stream := StreamServerInterceptor(config)
+	streamErr := stream(nil, &testServerStream{ctx: authorized}, &grpc.StreamServerInfo{FullMethod: "/test.Service/Watch"},
+		func(srv interface{}, ss grpc.ServerStream) error {
+			if _, ok := bearer.AccessTokenFromContext(ss.Context()); !ok {
+				return errors.New("expected access token in the stream context")
+			}
+			return nil
+		})
+	if streamErr != nil {
+		t.Fatalf("expected authenticated stream but received '%v'", streamErr)
+	}
+}
+
+func TestCertificateBinding(t *testing.T) {
+	// the thumbprint covers the raw DER bytes only:
+	client, other := &x509.Certificate{Raw: []byte("client")}, &x509.Certificate{Raw: []byte("other")}
+	factory := tokenstest.MustNew(t, nil)
+	rawToken := factory.MustSign(t, tokenstest.KeycloakAccessToken, tokenstest.WithClaim("cnf", map[string]interface{}{
+		mtls.ConfirmationMethod: mtls.Thumbprint(client),
+	}))
+	unary := UnaryServerInterceptor(&Config{CertificateBinding: &mtls.Binding{}, JWKS: factory.JWKS()})
+	call := func(certificate *x509.Certificate) error {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+rawToken))
+		if certificate != nil {
+			ctx = peer.NewContext(ctx, &peer.Peer{AuthInfo: credentials.TLSInfo{
+				State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{certificate}},
+			}})
+		}
+		_, err := unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Read"},
+			func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil })
+		return err
+	}
+
+	if err := call(client); err != nil {
+		t.Fatalf("expected the certificate-bound token to be accepted but received '%v'", err)
+	}
+	for _, certificate := range []*x509.Certificate{other, nil} {
+		err := call(certificate)
+		if status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("expected unauthenticated status but received '%v'", err)
+		}
+		details := status.Convert(err).Details()
+		if len(details) != 1 || details[0].(*errdetails.ErrorInfo).Reason != bearer.ErrorCodeInvalidToken {
+			t.Fatalf("expected invalid token error details but received '%v'", details)
+		}
+	}
+}
+
+func TestDPoP(t *testing.T) {
+	proofer, _ := dpop.NewProofer(jose.ES256)
+	factory := tokenstest.MustNew(t, nil)
+	rawToken := factory.MustSign(t, tokenstest.KeycloakAccessToken, tokenstest.WithClaim("cnf", map[string]interface{}{
+		"jkt": proofer.Thumbprint(),
+	}))
+	call := func(config *Config, pairs ...string) error {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(pairs...))
+		_, err := UnaryServerInterceptor(config)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Read"},
+			func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil })
+		return err
+	}
+	proof, _ := proofer.Proof("POST", "http://api.example.com/test.Service/Read", rawToken, "")
+
+	for _, config := range []*Config{{JWKS: factory.JWKS()}, {DPoP: dpop.NewVerifier(nil), JWKS: factory.JWKS()}} {
+		if err := call(config, "authorization", "Bearer "+rawToken, "dpop", proof); status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("expected the DPoP-bound token sent as bearer to be rejected but received '%v'", err)
+		}
+	}
+	config := &Config{DPoP: dpop.NewVerifier(nil), JWKS: factory.JWKS()}
+	if err := call(config, "authorization", "DPoP "+rawToken, ":authority", "api.example.com", "dpop", proof); err != nil {
+		t.Fatalf("expected the DPoP-bound token with the proof to be accepted but received '%v'", err)
+	}
+	err := call(config, "authorization", "DPoP "+rawToken, ":authority", "api.example.com")
+	details := status.Convert(err).Details()
+	if status.Code(err) != codes.Unauthenticated || len(details) != 1 || details[0].(*errdetails.ErrorInfo).Reason != dpop.ErrorCodeInvalidProof {
+		t.Fatalf("expected the invalid_dpop_proof status without the proof but received '%v'", err)
+	}
+}
+
+func TestPerRPCCredentials(t *testing.T) {
+	credentials := PerRPCCredentials(tokens.TokenSourceFunc(func(ctx context.Context) (tokens.JWT, error) {
+		return tokens.DefaultJWT([]byte(`{"access_token":"token","expires_in":60}`))
+	}), true)
+	md, err := credentials.GetRequestMetadata(context.Background())
+	if err != nil || md["authorization"] != "Bearer token" {
+		t.Fatalf("expected authorization metadata but received '%v', '%v'", md, err)
+	}
+	if !credentials.RequireTransportSecurity() {
+		t.Fatal("expected transport security to be required")
+	}
+}
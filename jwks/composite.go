@@ -0,0 +1,60 @@
+package jwks
+
+import (
+	"gopkg.in/square/go-jose.v2"
+)
+
+// Source is a named JWKS used by the composite JWKS.
+type Source struct {
+	Name string
+	JWKS JWKS
+}
+
+type compositeJWKS struct {
+	sources []Source
+}
+
+// Composite returns a JWKS merging the key sources, for example a remote JWKS of the current
+// identity provider, a file with pre-staged keys and a remote JWKS of the previous provider.
+// Sources take precedence in the order given: Key returns the keys of the first source knowing
+// the key ID and ReadSigned returns the result of the first source verifying the token.
+// When no source verifies the token, the first error other than ErrSigningKeyNotKnown is returned.
+// The Source of a successful read is the name of the verifying source.
+func Composite(sources ...Source) JWKS {
+	return &compositeJWKS{sources: sources}
+}
+
+func (c *compositeJWKS) Key(kid string) []jose.JSONWebKey {
+	for _, source := range c.sources {
+		if keys := source.JWKS.Key(kid); len(keys) > 0 {
+			return keys
+		}
+	}
+	return []jose.JSONWebKey{}
+}
+
+func (c *compositeJWKS) ReadSigned(rawToken string) JWTRead {
+	var failed JWTRead
+	for _, source := range c.sources {
+		read := source.JWKS.ReadSigned(rawToken)
+		if read.Error() == nil {
+			return &sourcedJWTRead{JWTRead: read, source: source.Name}
+		}
+		if failed == nil || (failed.Error() == ErrSigningKeyNotKnown && read.Error() != ErrSigningKeyNotKnown) {
+			failed = read
+		}
+	}
+	if failed == nil {
+		return &defaultJWTRead{err: ErrSigningKeyNotKnown}
+	}
+	return failed
+}
+
+type sourcedJWTRead struct {
+	JWTRead
+	source string
+}
+
+func (rr *sourcedJWTRead) Source() string {
+	return rr.source
+}
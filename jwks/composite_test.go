@@ -0,0 +1,56 @@
+package jwks
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+
+	"gopkg.in/square/go-jose.v2"
+)
+
+func TestComposite(t *testing.T) {
+	oldKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	newKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	stagedKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	oldSet, _ := FromPublicKey("old", &oldKey.PublicKey)
+	newSet, _ := FromPublicKey("shared", &newKey.PublicKey)
+	stagedSet := FromKeySet(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: &stagedKey.PublicKey, KeyID: "next"},
+		{Key: &stagedKey.PublicKey, KeyID: "shared"},
+	}})
+	keySet := Composite(Source{Name: "new", JWKS: newSet}, Source{Name: "staged", JWKS: stagedSet}, Source{Name: "old", JWKS: oldSet})
+
+	for kid, expected := range map[string]struct {
+		key    *rsa.PrivateKey
+		source string
+	}{
+		"old":    {oldKey, "old"},
+		"shared": {newKey, "new"},
+		"next":   {stagedKey, "staged"},
+	} {
+		read := keySet.ReadSigned(signTestToken(t, expected.key, jose.RS256, kid))
+		if read.Error() != nil || read.Source() != expected.source {
+			t.Fatalf("expected token '%s' to be verified by '%s' but received '%v', '%v'", kid, expected.source, read.Source(), read.Error())
+		}
+	}
+
+	if keys := keySet.Key("shared"); len(keys) != 1 || keys[0].Key.(*rsa.PublicKey).N.Cmp(newKey.PublicKey.N) != 0 {
+		t.Fatalf("expected the key of the first source but received '%v'", keys)
+	}
+
+	// the staged source holds a different key under the shared key ID:
+	read := keySet.ReadSigned(signTestToken(t, stagedKey, jose.RS256, "shared"))
+	if read.Error() != nil || read.Source() != "staged" {
+		t.Fatalf("expected the token to be verified by the staged source but received '%v', '%v'", read.Source(), read.Error())
+	}
+
+	unknownKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	if read := keySet.ReadSigned(signTestToken(t, unknownKey, jose.RS256, "unknown")); read.Error() != ErrSigningKeyNotKnown {
+		t.Fatalf("expected ErrSigningKeyNotKnown but received '%v'", read.Error())
+	}
+	read = keySet.ReadSigned(signTestToken(t, unknownKey, jose.RS256, "old"))
+	if read.Error() == nil || !strings.Contains(read.Error().Error(), "crypto") || read.Source() != "" {
+		t.Fatalf("expected a verification error but received '%v'", read.Error())
+	}
+}
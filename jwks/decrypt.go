@@ -0,0 +1,189 @@
+package jwks
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/radekg/app-kit-tokens/tokens"
+	"gopkg.in/square/go-jose.v2"
+)
+
+var (
+	// ErrContentEncryptionNotAllowed indicates a token encrypted with an enc value not allowed by the decryptor.
+	ErrContentEncryptionNotAllowed = errContentEncryptionNotAllowed()
+	// ErrDecryptionKeyNotKnown indicates a token for which there is no decryption key.
+	ErrDecryptionKeyNotKnown = errDecryptionKeyNotKnown()
+	// ErrEncryptionRequired indicates a signed token where an encrypted token is required.
+	ErrEncryptionRequired = errEncryptionRequired()
+	// ErrKeyAlgorithmNotAllowed indicates a token encrypted with an alg value not allowed by the decryptor.
+	ErrKeyAlgorithmNotAllowed = errKeyAlgorithmNotAllowed()
+	// ErrNotNestedToken indicates an encrypted token which does not contain a signed token.
+	ErrNotNestedToken = errNotNestedToken()
+)
+
+func errContentEncryptionNotAllowed() error { return errors.New("content encryption not allowed") }
+func errDecryptionKeyNotKnown() error       { return errors.New("no decryption key for the token") }
+func errEncryptionRequired() error          { return errors.New("encrypted token required") }
+func errKeyAlgorithmNotAllowed() error      { return errors.New("key management algorithm not allowed") }
+func errNotNestedToken() error              { return errors.New("encrypted token does not contain a signed token") }
+
+// DefaultKeyAlgorithms are the key management algorithms allowed when none are configured.
+// RSA1_5 is not allowed by default.
+var DefaultKeyAlgorithms = []jose.KeyAlgorithm{
+	jose.RSA_OAEP, jose.RSA_OAEP_256,
+	jose.ECDH_ES, jose.ECDH_ES_A128KW, jose.ECDH_ES_A192KW, jose.ECDH_ES_A256KW,
+}
+
+// DefaultContentEncryptions are the content encryption algorithms allowed when none are configured.
+var DefaultContentEncryptions = []jose.ContentEncryption{
+	jose.A128GCM, jose.A192GCM, jose.A256GCM,
+	jose.A128CBC_HS256, jose.A192CBC_HS384, jose.A256CBC_HS512,
+}
+
+// DecryptorConfig represents the decryptor configuration.
+type DecryptorConfig struct {
+	// ContentEncryptions: the allowed enc values, DefaultContentEncryptions if empty.
+	ContentEncryptions []jose.ContentEncryption
+	// KeyAlgorithms: the allowed alg values, DefaultKeyAlgorithms if empty.
+	// Symmetric keys require dir or the AES key wrap algorithms to be listed explicitly.
+	KeyAlgorithms []jose.KeyAlgorithm
+	// Keys: the private decryption keys or the []byte shared keys of dir and AES key wrap.
+	// A token with the kid header is decrypted only with the key of the same key ID,
+	// otherwise every key is tried.
+	Keys []jose.JSONWebKey
+}
+
+// Decryptor decrypts nested JWS-in-JWE tokens.
+type Decryptor interface {
+	// Decrypt returns the inner signed token of a compact serialized encrypted token.
+	Decrypt(rawToken string) (string, error)
+	// ReadEncrypted decrypts the token and reads the inner signed token with the key set.
+	ReadEncrypted(rawToken string, keySet JWKS) JWTRead
+}
+
+type defaultDecryptor struct {
+	config *DecryptorConfig
+}
+
+// NewDecryptor returns a decryptor for the configured keys.
+func NewDecryptor(config *DecryptorConfig) (Decryptor, error) {
+	if len(config.Keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	for _, key := range config.Keys {
+		if symmetricKey, ok := key.Key.([]byte); ok {
+			if !validSymmetricKeySize(len(symmetricKey)) {
+				return nil, fmt.Errorf("invalid symmetric decryption key '%s' size %d", key.KeyID, len(symmetricKey))
+			}
+			continue
+		}
+		if !key.Valid() || key.IsPublic() {
+			return nil, fmt.Errorf("invalid decryption key '%s'", key.KeyID)
+		}
+	}
+	return &defaultDecryptor{config: config}, nil
+}
+
+// validSymmetricKeySize checks the size against the AES key wrap key sizes
+// and the content encryption key sizes used with dir.
+func validSymmetricKeySize(size int) bool {
+	switch size {
+	case 16, 24, 32, 48, 64:
+		return true
+	default:
+		return false
+	}
+}
+
+func (d *defaultDecryptor) Decrypt(rawToken string) (string, error) {
+	if !tokens.IsEncrypted(rawToken) {
+		return "", ErrEncryptionRequired
+	}
+	encrypted, err := jose.ParseEncrypted(rawToken)
+	if err != nil {
+		return "", err
+	}
+	if !d.keyAlgorithmAllowed(encrypted.Header.Algorithm) {
+		return "", ErrKeyAlgorithmNotAllowed
+	}
+	enc, _ := encrypted.Header.ExtraHeaders["enc"].(string)
+	if !d.contentEncryptionAllowed(enc) {
+		return "", ErrContentEncryptionNotAllowed
+	}
+	var lastErr error = ErrDecryptionKeyNotKnown
+	for _, key := range d.config.Keys {
+		if encrypted.Header.KeyID != "" && key.KeyID != encrypted.Header.KeyID {
+			continue
+		}
+		payload, err := encrypted.Decrypt(key.Key)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		// the cty header should be JWT for nested tokens but not every issuer sets it:
+		cty, _ := encrypted.Header.ExtraHeaders["cty"].(string)
+		if (cty != "" && !strings.EqualFold(cty, "JWT")) || strings.Count(string(payload), ".") != 2 {
+			return "", ErrNotNestedToken
+		}
+		return string(payload), nil
+	}
+	return "", lastErr
+}
+
+func (d *defaultDecryptor) ReadEncrypted(rawToken string, keySet JWKS) JWTRead {
+	inner, err := d.Decrypt(rawToken)
+	if err != nil {
+		return &defaultJWTRead{err: err}
+	}
+	return keySet.ReadSigned(inner)
+}
+
+func (d *defaultDecryptor) keyAlgorithmAllowed(algorithm string) bool {
+	allowed := d.config.KeyAlgorithms
+	if len(allowed) == 0 {
+		allowed = DefaultKeyAlgorithms
+	}
+	for _, value := range allowed {
+		if string(value) == algorithm {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *defaultDecryptor) contentEncryptionAllowed(enc string) bool {
+	allowed := d.config.ContentEncryptions
+	if len(allowed) == 0 {
+		allowed = DefaultContentEncryptions
+	}
+	for _, value := range allowed {
+		if string(value) == enc {
+			return true
+		}
+	}
+	return false
+}
+
+type decryptingJWKS struct {
+	JWKS
+	decryptor         Decryptor
+	requireEncryption bool
+}
+
+// WithDecryption returns a JWKS reading both encrypted and signed tokens, encrypted tokens
+// are decrypted before the inner token is read with the key set. Signed tokens are rejected
+// with ErrEncryptionRequired if requireEncryption is set.
+func WithDecryption(keySet JWKS, decryptor Decryptor, requireEncryption bool) JWKS {
+	return &decryptingJWKS{JWKS: keySet, decryptor: decryptor, requireEncryption: requireEncryption}
+}
+
+func (d *decryptingJWKS) ReadSigned(rawToken string) JWTRead {
+	if tokens.IsEncrypted(rawToken) {
+		return d.decryptor.ReadEncrypted(rawToken, d.JWKS)
+	}
+	if d.requireEncryption {
+		return &defaultJWTRead{err: ErrEncryptionRequired}
+	}
+	return d.JWKS.ReadSigned(rawToken)
+}
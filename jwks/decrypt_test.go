@@ -0,0 +1,142 @@
+package jwks
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/radekg/app-kit-tokens/tokens"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func encryptTestToken(t *testing.T, payload string, recipient jose.Recipient, enc jose.ContentEncryption) string {
+	encrypter, err := jose.NewEncrypter(enc, recipient, (&jose.EncrypterOptions{}).WithContentType("JWT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := encrypter.Encrypt([]byte(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawToken, err := encrypted.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rawToken
+}
+
+func TestDecryptor(t *testing.T) {
+	signingKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keySet, _ := FromPublicKey("signing", &signingKey.PublicKey)
+	signed := signTestToken(t, signingKey, jose.RS256, "signing")
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	decryptor, err := NewDecryptor(&DecryptorConfig{Keys: []jose.JSONWebKey{
+		{Key: rsaKey, KeyID: "rsa"},
+		{Key: ecKey, KeyID: "ec"},
+	}})
+	if err != nil {
+		t.Fatalf("expected decryptor but received '%v'", err)
+	}
+
+	for name, rawToken := range map[string]string{
+		"RSA-OAEP":       encryptTestToken(t, signed, jose.Recipient{Algorithm: jose.RSA_OAEP, Key: &rsaKey.PublicKey, KeyID: "rsa"}, jose.A256GCM),
+		"ECDH-ES+A256KW": encryptTestToken(t, signed, jose.Recipient{Algorithm: jose.ECDH_ES_A256KW, Key: &ecKey.PublicKey, KeyID: "ec"}, jose.A128CBC_HS256),
+		"without kid":    encryptTestToken(t, signed, jose.Recipient{Algorithm: jose.ECDH_ES, Key: &ecKey.PublicKey}, jose.A256GCM),
+	} {
+		if !tokens.IsEncrypted(rawToken) {
+			t.Fatalf("expected '%s' token to be recognized as encrypted", name)
+		}
+		read := decryptor.ReadEncrypted(rawToken, keySet)
+		if read.Error() != nil {
+			t.Fatalf("expected '%s' token to decrypt and verify but received '%v'", name, read.Error())
+		}
+		if sub, _ := read.Claims().GetClaimMustString("sub"); sub != "subject" {
+			t.Fatalf("expected the inner token claims but received '%v'", read.Claims())
+		}
+	}
+
+	for expected, rawToken := range map[error]string{
+		ErrKeyAlgorithmNotAllowed: encryptTestToken(t, signed, jose.Recipient{Algorithm: jose.RSA1_5, Key: &rsaKey.PublicKey, KeyID: "rsa"}, jose.A256GCM),
+		ErrDecryptionKeyNotKnown:  encryptTestToken(t, signed, jose.Recipient{Algorithm: jose.RSA_OAEP, Key: &rsaKey.PublicKey, KeyID: "unknown"}, jose.A256GCM),
+		ErrNotNestedToken:         encryptTestToken(t, `{"sub":"subject"}`, jose.Recipient{Algorithm: jose.RSA_OAEP, Key: &rsaKey.PublicKey, KeyID: "rsa"}, jose.A256GCM),
+		ErrEncryptionRequired:     signed,
+	} {
+		if _, err := decryptor.Decrypt(rawToken); err != expected {
+			t.Fatalf("expected '%v' but received '%v'", expected, err)
+		}
+	}
+
+	restricted, _ := NewDecryptor(&DecryptorConfig{
+		ContentEncryptions: []jose.ContentEncryption{jose.A256GCM},
+		Keys:               []jose.JSONWebKey{{Key: rsaKey, KeyID: "rsa"}},
+	})
+	rawToken := encryptTestToken(t, signed, jose.Recipient{Algorithm: jose.RSA_OAEP, Key: &rsaKey.PublicKey, KeyID: "rsa"}, jose.A128GCM)
+	if _, err := restricted.Decrypt(rawToken); err != ErrContentEncryptionNotAllowed {
+		t.Fatalf("expected ErrContentEncryptionNotAllowed but received '%v'", err)
+	}
+
+	if _, err := NewDecryptor(&DecryptorConfig{Keys: []jose.JSONWebKey{{Key: &rsaKey.PublicKey}}}); err == nil {
+		t.Fatal("expected a public key to be rejected")
+	}
+}
+
+func TestDecryptorSymmetricKeys(t *testing.T) {
+	signingKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keySet, _ := FromPublicKey("signing", &signingKey.PublicKey)
+	signed := signTestToken(t, signingKey, jose.RS256, "signing")
+
+	wrapKey, directKey := make([]byte, 32), make([]byte, 32)
+	rand.Read(wrapKey)
+	rand.Read(directKey)
+	decryptor, err := NewDecryptor(&DecryptorConfig{
+		KeyAlgorithms: []jose.KeyAlgorithm{jose.A256KW, jose.DIRECT},
+		Keys: []jose.JSONWebKey{
+			{Key: wrapKey, KeyID: "wrap"},
+			{Key: directKey, KeyID: "direct"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected decryptor with symmetric keys but received '%v'", err)
+	}
+	for name, rawToken := range map[string]string{
+		"A256KW": encryptTestToken(t, signed, jose.Recipient{Algorithm: jose.A256KW, Key: wrapKey, KeyID: "wrap"}, jose.A256GCM),
+		"dir":    encryptTestToken(t, signed, jose.Recipient{Algorithm: jose.DIRECT, Key: directKey, KeyID: "direct"}, jose.A256GCM),
+	} {
+		if read := decryptor.ReadEncrypted(rawToken, keySet); read.Error() != nil {
+			t.Fatalf("expected '%s' token to decrypt and verify but received '%v'", name, read.Error())
+		}
+	}
+
+	defaults, _ := NewDecryptor(&DecryptorConfig{Keys: []jose.JSONWebKey{{Key: wrapKey, KeyID: "wrap"}}})
+	rawToken := encryptTestToken(t, signed, jose.Recipient{Algorithm: jose.A256KW, Key: wrapKey, KeyID: "wrap"}, jose.A256GCM)
+	if _, err := defaults.Decrypt(rawToken); err != ErrKeyAlgorithmNotAllowed {
+		t.Fatalf("expected ErrKeyAlgorithmNotAllowed without explicitly allowed symmetric algorithms but received '%v'", err)
+	}
+	if _, err := NewDecryptor(&DecryptorConfig{Keys: []jose.JSONWebKey{{Key: []byte("short")}}}); err == nil {
+		t.Fatal("expected a symmetric key of invalid size to be rejected")
+	}
+}
+
+func TestWithDecryption(t *testing.T) {
+	signingKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keySet, _ := FromPublicKey("signing", &signingKey.PublicKey)
+	signed := signTestToken(t, signingKey, jose.RS256, "signing")
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	decryptor, _ := NewDecryptor(&DecryptorConfig{Keys: []jose.JSONWebKey{{Key: rsaKey, KeyID: "rsa"}}})
+	encrypted := encryptTestToken(t, signed, jose.Recipient{Algorithm: jose.RSA_OAEP_256, Key: &rsaKey.PublicKey, KeyID: "rsa"}, jose.A256GCM)
+
+	optional := WithDecryption(keySet, decryptor, false)
+	if read := optional.ReadSigned(encrypted); read.Error() != nil {
+		t.Fatalf("expected encrypted token to be read but received '%v'", read.Error())
+	}
+	if read := optional.ReadSigned(signed); read.Error() != nil {
+		t.Fatalf("expected signed token to be read but received '%v'", read.Error())
+	}
+	if read := WithDecryption(keySet, decryptor, true).ReadSigned(signed); read.Error() != ErrEncryptionRequired {
+		t.Fatalf("expected ErrEncryptionRequired but received '%v'", read.Error())
+	}
+}
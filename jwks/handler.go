@@ -0,0 +1,51 @@
+package jwks
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+)
+
+// Handler returns an http.Handler serving the key set returned by keys, for example the
+// VerificationKeys method of a signer key ring. Only the public parts of asymmetric keys
+// are served. Responses carry an ETag, conditional requests with a matching If-None-Match
+// header receive 304 Not Modified, and clients may cache the response for maxAge.
+func Handler(keys func() jose.JSONWebKeySet, maxAge time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		set := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
+		for _, key := range keys().Keys {
+			if _, ok := key.Key.([]byte); ok || !key.Valid() {
+				continue
+			}
+			set.Keys = append(set.Keys, key.Public())
+		}
+		body, err := json.Marshal(set)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		sum := sha256.Sum256(body)
+		etag := `"` + base64.RawURLEncoding.EncodeToString(sum[:]) + `"`
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int64(maxAge.Seconds())))
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/jwk-set+json")
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			w.Write(body)
+		}
+	})
+}
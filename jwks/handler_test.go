@@ -0,0 +1,58 @@
+package jwks
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+)
+
+func TestHandler(t *testing.T) {
+	privateKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keys := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: privateKey, KeyID: "rsa", Algorithm: string(jose.RS256), Use: "sig"},
+		{Key: []byte("symmetric secret"), KeyID: "hmac", Algorithm: string(jose.HS256)},
+	}}
+	testServer := httptest.NewServer(Handler(func() jose.JSONWebKeySet { return keys }, 5*time.Minute))
+	defer testServer.Close()
+
+	resp, err := http.Get(testServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := jose.JSONWebKeySet{}
+	json.NewDecoder(resp.Body).Decode(&served)
+	resp.Body.Close()
+	if len(served.Keys) != 1 || !served.Keys[0].IsPublic() || served.Keys[0].KeyID != "rsa" {
+		t.Fatalf("expected the public RSA key only but received '%v'", served.Keys)
+	}
+	if cacheControl := resp.Header.Get("Cache-Control"); cacheControl != "public, max-age=300" {
+		t.Fatalf("expected Cache-Control header but received '%s'", cacheControl)
+	}
+
+	request, _ := http.NewRequest(http.MethodGet, testServer.URL, nil)
+	request.Header.Set("If-None-Match", resp.Header.Get("ETag"))
+	notModified, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notModified.Body.Close()
+	if notModified.StatusCode != http.StatusNotModified {
+		t.Fatalf("expected 304 Not Modified but received '%d'", notModified.StatusCode)
+	}
+
+	location, _ := url.Parse(testServer.URL)
+	keySet, err := ResolveJWKS(location, nil)
+	if err != nil {
+		t.Fatalf("expected the served key set to resolve but received '%v'", err)
+	}
+	if read := keySet.ReadSigned(signTestToken(t, privateKey, jose.RS256, "rsa")); read.Error() != nil {
+		t.Fatalf("expected token to verify but received '%v'", read.Error())
+	}
+}
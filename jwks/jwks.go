@@ -106,6 +106,9 @@ type JWTRead interface {
 	Error() error
 	Headers() []jose.Header
 	Claims() tokens.Claims
+	// Source returns the name of the key source which verified the token,
+	// empty if the JWKS does not name its sources.
+	Source() string
 }
 
 type defaultJWTRead struct {
@@ -124,3 +127,7 @@ func (rr *defaultJWTRead) Headers() []jose.Header {
 func (rr *defaultJWTRead) Claims() tokens.Claims {
 	return rr.claims
 }
+
+func (rr *defaultJWTRead) Source() string {
+	return ""
+}
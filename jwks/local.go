@@ -0,0 +1,318 @@
+package jwks
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+)
+
+var (
+	// ErrInvalidWatchInterval indicates a watch interval which is not positive.
+	ErrInvalidWatchInterval = errInvalidWatchInterval()
+	// ErrNoKeys indicates a source without any verification keys.
+	ErrNoKeys = errNoKeys()
+	// ErrUnsupportedPEMBlock indicates a PEM block which is neither a public key nor a certificate.
+	ErrUnsupportedPEMBlock = errUnsupportedPEMBlock()
+)
+
+func errInvalidWatchInterval() error { return errors.New("watch interval must be positive") }
+func errNoKeys() error               { return errors.New("no verification keys found") }
+func errUnsupportedPEMBlock() error  { return errors.New("unsupported PEM block") }
+
+// FromKeySet returns a JWKS for an in memory key set.
+// Private keys are reduced to their public parts.
+func FromKeySet(set jose.JSONWebKeySet) JWKS {
+	keys := make([]jose.JSONWebKey, 0, len(set.Keys))
+	for _, key := range set.Keys {
+		keys = append(keys, key.Public())
+	}
+	return &defaultJWKS{set: &jose.JSONWebKeySet{Keys: keys}}
+}
+
+// FromPublicKey returns a JWKS containing a single public key with the given key ID.
+func FromPublicKey(kid string, key crypto.PublicKey) (JWKS, error) {
+	jwk := jose.JSONWebKey{Key: key, KeyID: kid, Use: "sig"}
+	if !jwk.Valid() || !jwk.IsPublic() {
+		return nil, fmt.Errorf("invalid public key of type %T", key)
+	}
+	return FromKeySet(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{jwk}}), nil
+}
+
+// FromJSON returns a JWKS for a JSON encoded key set.
+func FromJSON(data []byte) (JWKS, error) {
+	set := jose.JSONWebKeySet{}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	if len(set.Keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	return FromKeySet(set), nil
+}
+
+// FromJSONFile returns a JWKS for a JSON encoded key set stored in a file.
+func FromJSONFile(path string) (JWKS, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return FromJSON(data)
+}
+
+// FromPEM returns a JWKS for PEM encoded public keys and X.509 certificates.
+// Supported blocks are PUBLIC KEY, RSA PUBLIC KEY and CERTIFICATE.
+// The key ID of every key is its RFC 7638 SHA-256 thumbprint.
+func FromPEM(data []byte) (JWKS, error) {
+	keys, err := parsePEMKeys(data)
+	if err != nil {
+		return nil, err
+	}
+	for index := range keys {
+		if keys[index].KeyID, err = thumbprint(keys[index]); err != nil {
+			return nil, err
+		}
+	}
+	return FromKeySet(jose.JSONWebKeySet{Keys: keys}), nil
+}
+
+// FromPEMDirectory returns a JWKS for all .pem, .crt and .cer files in a directory.
+// The key ID of a key is the file name without the extension when the file contains
+// a single key, the RFC 7638 SHA-256 thumbprint otherwise.
+func FromPEMDirectory(dir string) (JWKS, error) {
+	paths, err := pemFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	all := []jose.JSONWebKey{}
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		keys, err := parsePEMKeys(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		for index := range keys {
+			if len(keys) == 1 {
+				keys[index].KeyID = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+			} else if keys[index].KeyID, err = thumbprint(keys[index]); err != nil {
+				return nil, err
+			}
+		}
+		all = append(all, keys...)
+	}
+	if len(all) == 0 {
+		return nil, ErrNoKeys
+	}
+	return FromKeySet(jose.JSONWebKeySet{Keys: all}), nil
+}
+
+// WatchedJWKS is a JWKS reloaded when its source changes.
+type WatchedJWKS interface {
+	JWKS
+	// Close stops watching the source.
+	Close() error
+}
+
+// WatchJSONFile returns a JWKS for a JSON encoded key set stored in a file, checking the file
+// for changes every interval. The previously loaded keys stay in use when reloading fails,
+// reload errors are passed to onError, if set, once for every version of the source which fails
+// to load. The interval must be positive.
+func WatchJSONFile(path string, interval time.Duration, onError func(error)) (WatchedJWKS, error) {
+	return watch(func() (string, error) {
+		return fingerprint([]string{path})
+	}, func() (JWKS, error) {
+		return FromJSONFile(path)
+	}, interval, onError)
+}
+
+// WatchPEMDirectory returns a JWKS for the PEM files in a directory, checking the directory
+// for changes every interval. The previously loaded keys stay in use when reloading fails,
+// reload errors are passed to onError, if set, once for every version of the source which fails
+// to load. The interval must be positive.
+func WatchPEMDirectory(dir string, interval time.Duration, onError func(error)) (WatchedJWKS, error) {
+	return watch(func() (string, error) {
+		paths, err := pemFiles(dir)
+		if err != nil {
+			return "", err
+		}
+		return fingerprint(paths)
+	}, func() (JWKS, error) {
+		return FromPEMDirectory(dir)
+	}, interval, onError)
+}
+
+type watchedJWKS struct {
+	sync.RWMutex
+	current JWKS
+	done    chan struct{}
+	once    sync.Once
+}
+
+func watch(fingerprintFunc func() (string, error), load func() (JWKS, error), interval time.Duration, onError func(error)) (WatchedJWKS, error) {
+	if interval <= 0 {
+		return nil, ErrInvalidWatchInterval
+	}
+	lastFingerprint, err := fingerprintFunc()
+	if err != nil {
+		return nil, err
+	}
+	current, err := load()
+	if err != nil {
+		return nil, err
+	}
+	watched := &watchedJWKS{current: current, done: make(chan struct{})}
+	reportError := func(err error) {
+		if onError != nil {
+			onError(err)
+		}
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		// every broken version of the source is reported once:
+		fingerprintFailed := false
+		for {
+			select {
+			case <-watched.done:
+				return
+			case <-ticker.C:
+				newFingerprint, err := fingerprintFunc()
+				if err != nil {
+					if !fingerprintFailed {
+						reportError(err)
+					}
+					fingerprintFailed = true
+					continue
+				}
+				fingerprintFailed = false
+				if newFingerprint == lastFingerprint {
+					continue
+				}
+				lastFingerprint = newFingerprint
+				reloaded, err := load()
+				if err != nil {
+					reportError(err)
+					continue
+				}
+				watched.Lock()
+				watched.current = reloaded
+				watched.Unlock()
+			}
+		}
+	}()
+	return watched, nil
+}
+
+func (w *watchedJWKS) Close() error {
+	w.once.Do(func() { close(w.done) })
+	return nil
+}
+
+func (w *watchedJWKS) Key(kid string) []jose.JSONWebKey {
+	w.RLock()
+	defer w.RUnlock()
+	return w.current.Key(kid)
+}
+
+func (w *watchedJWKS) ReadSigned(rawToken string) JWTRead {
+	w.RLock()
+	defer w.RUnlock()
+	return w.current.ReadSigned(rawToken)
+}
+
+func parsePEMKeys(data []byte) ([]jose.JSONWebKey, error) {
+	keys := []jose.JSONWebKey{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "PUBLIC KEY":
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, jose.JSONWebKey{Key: key, Use: "sig"})
+		case "RSA PUBLIC KEY":
+			key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, jose.JSONWebKey{Key: key, Use: "sig"})
+		case "CERTIFICATE":
+			certificate, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, jose.JSONWebKey{
+				Key:          certificate.PublicKey,
+				Certificates: []*x509.Certificate{certificate},
+				Use:          "sig",
+			})
+		default:
+			return nil, ErrUnsupportedPEMBlock
+		}
+	}
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	return keys, nil
+}
+
+func pemFiles(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{}
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(info.Name())) {
+		case ".pem", ".crt", ".cer":
+			paths = append(paths, filepath.Join(dir, info.Name()))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// fingerprint identifies the state of the files by their names and contents.
+func fingerprint(paths []string) (string, error) {
+	hash := sha256.New()
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		hash.Write([]byte(path))
+		hash.Write(data)
+	}
+	return base64.RawURLEncoding.EncodeToString(hash.Sum(nil)), nil
+}
+
+func thumbprint(key jose.JSONWebKey) (string, error) {
+	value, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(value), nil
+}
@@ -0,0 +1,182 @@
+package jwks
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func signTestToken(t *testing.T, key interface{}, algorithm jose.SignatureAlgorithm, kid string) string {
+	options := (&jose.SignerOptions{}).WithType("JWT")
+	if kid != "" {
+		options = options.WithHeader("kid", kid)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: algorithm, Key: key}, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawToken, err := jwt.Signed(signer).Claims(map[string]interface{}{"sub": "subject"}).CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rawToken
+}
+
+func TestFromKeySetAndPublicKey(t *testing.T) {
+	privateKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keySet := FromKeySet(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: privateKey, KeyID: "private"}}})
+	if keys := keySet.Key("private"); len(keys) != 1 || !keys[0].IsPublic() {
+		t.Fatalf("expected a single public key but received '%v'", keys)
+	}
+	if read := keySet.ReadSigned(signTestToken(t, privateKey, jose.RS256, "private")); read.Error() != nil {
+		t.Fatalf("expected token to verify but received '%v'", read.Error())
+	}
+
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	keySet, err := FromPublicKey("ec", &ecKey.PublicKey)
+	if err != nil {
+		t.Fatalf("expected JWKS but received '%v'", err)
+	}
+	if read := keySet.ReadSigned(signTestToken(t, ecKey, jose.ES256, "ec")); read.Error() != nil {
+		t.Fatalf("expected token to verify but received '%v'", read.Error())
+	}
+	if _, err := FromPublicKey("invalid", "not a key"); err == nil {
+		t.Fatal("expected an invalid key error")
+	}
+}
+
+func TestFromJSONFile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "jwks")
+	defer os.RemoveAll(dir)
+	privateKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	data, _ := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &privateKey.PublicKey, KeyID: "file"}}})
+	path := filepath.Join(dir, "jwks.json")
+	ioutil.WriteFile(path, data, 0644)
+
+	keySet, err := FromJSONFile(path)
+	if err != nil {
+		t.Fatalf("expected JWKS but received '%v'", err)
+	}
+	if read := keySet.ReadSigned(signTestToken(t, privateKey, jose.RS256, "file")); read.Error() != nil {
+		t.Fatalf("expected token to verify but received '%v'", read.Error())
+	}
+	if _, err := FromJSON([]byte(`{"keys":[]}`)); err != ErrNoKeys {
+		t.Fatalf("expected ErrNoKeys but received '%v'", err)
+	}
+}
+
+func TestFromPEM(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "jwks")
+	defer os.RemoveAll(dir)
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	der, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	ioutil.WriteFile(filepath.Join(dir, "rsa.pem"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644)
+
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "signer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certificate, _ := x509.CreateCertificate(rand.Reader, template, template, &ecKey.PublicKey, ecKey)
+	ioutil.WriteFile(filepath.Join(dir, "ec.crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "README.txt"), []byte("ignored"), 0644)
+
+	keySet, err := FromPEMDirectory(dir)
+	if err != nil {
+		t.Fatalf("expected JWKS but received '%v'", err)
+	}
+	if read := keySet.ReadSigned(signTestToken(t, rsaKey, jose.RS256, "rsa")); read.Error() != nil {
+		t.Fatalf("expected RSA token to verify but received '%v'", read.Error())
+	}
+	if read := keySet.ReadSigned(signTestToken(t, ecKey, jose.ES256, "ec")); read.Error() != nil {
+		t.Fatalf("expected certificate token to verify but received '%v'", read.Error())
+	}
+	if keys := keySet.Key("ec"); len(keys) != 1 || len(keys[0].Certificates) != 1 {
+		t.Fatalf("expected the certificate to be retained but received '%v'", keys)
+	}
+
+	keySet, err = FromPEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("expected JWKS but received '%v'", err)
+	}
+	if read := keySet.ReadSigned(signTestToken(t, rsaKey, jose.RS256, "")); read.Error() != nil {
+		t.Fatalf("expected token without kid to verify but received '%v'", read.Error())
+	}
+	if _, err := FromPEM(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte{}})); err != ErrUnsupportedPEMBlock {
+		t.Fatalf("expected ErrUnsupportedPEMBlock but received '%v'", err)
+	}
+}
+
+func TestWatchJSONFile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "jwks")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "jwks.json")
+	writeKey := func(kid string) *rsa.PrivateKey {
+		privateKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+		data, _ := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &privateKey.PublicKey, KeyID: kid}}})
+		ioutil.WriteFile(path, data, 0644)
+		return privateKey
+	}
+	writeKey("old")
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if _, err := WatchJSONFile(path, interval, nil); err != ErrInvalidWatchInterval {
+			t.Fatalf("expected ErrInvalidWatchInterval but received '%v'", err)
+		}
+	}
+
+	errs := make(chan error, 10)
+	keySet, err := WatchJSONFile(path, 10*time.Millisecond, func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	})
+	if err != nil {
+		t.Fatalf("expected watched JWKS but received '%v'", err)
+	}
+	defer keySet.Close()
+
+	ioutil.WriteFile(path, []byte("not json"), 0644)
+	select {
+	case <-errs:
+	case <-time.After(time.Second):
+		t.Fatal("expected a reload error")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if len(errs) != 0 {
+		t.Fatalf("expected the broken file to be reported once but received '%d' more errors", len(errs))
+	}
+	if keys := keySet.Key("old"); len(keys) != 1 {
+		t.Fatalf("expected previous keys to be retained but received '%v'", keys)
+	}
+
+	rotated := writeKey("new")
+	deadline := time.Now().Add(time.Second)
+	for len(keySet.Key("new")) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected rotated keys to be loaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if read := keySet.ReadSigned(signTestToken(t, rotated, jose.RS256, "new")); read.Error() != nil {
+		t.Fatalf("expected token to verify but received '%v'", read.Error())
+	}
+}
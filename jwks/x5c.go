@@ -0,0 +1,154 @@
+package jwks
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"time"
+
+	"github.com/radekg/app-kit-tokens/tokens"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+var (
+	// ErrCertificateKeyMismatch indicates a key whose leaf certificate holds a different public key.
+	ErrCertificateKeyMismatch = errCertificateKeyMismatch()
+	// ErrCertificateMissing indicates a key without the x5c certificate chain where one is required.
+	ErrCertificateMissing = errCertificateMissing()
+	// ErrCertificateUsage indicates a leaf certificate not permitting digital signatures.
+	ErrCertificateUsage = errCertificateUsage()
+	// ErrThumbprintMismatch indicates x5t or x5t#S256 not matching the leaf certificate.
+	ErrThumbprintMismatch = errThumbprintMismatch()
+)
+
+func errCertificateKeyMismatch() error {
+	return errors.New("certificate public key does not match the key")
+}
+func errCertificateMissing() error { return errors.New("key has no certificate chain") }
+func errCertificateUsage() error {
+	return errors.New("certificate key usage does not permit digital signatures")
+}
+func errThumbprintMismatch() error { return errors.New("certificate thumbprint mismatch") }
+
+// CertificateValidation represents the x5c certificate chain validation settings.
+type CertificateValidation struct {
+	// AllowKeysWithoutCertificates: accept keys without the x5c certificate chain, such keys are
+	// trusted without any validation. Keys without certificates are rejected by default.
+	AllowKeysWithoutCertificates bool
+	// ExtKeyUsages: the extended key usages required of the chain, any usage is accepted if empty.
+	ExtKeyUsages []x509.ExtKeyUsage
+	// Intermediates: intermediate certificates used when the chain does not verify
+	// with the intermediates from x5c, optional.
+	Intermediates *x509.CertPool
+	// Now: returns the time at which the certificates must be valid, defaults to time.Now.
+	Now func() time.Time
+	// Roots: the trusted root certificates, the system pool is used if nil.
+	Roots *x509.CertPool
+}
+
+// Validate validates the certificate chain of a key: the leaf certificate must hold the public
+// key of the JWK and match the x5t and x5t#S256 thumbprints, its key usage must permit digital
+// signatures and the chain must verify against the roots at the current time.
+// Keys without the certificate chain are rejected unless AllowKeysWithoutCertificates is set.
+func (v *CertificateValidation) Validate(key jose.JSONWebKey) error {
+	if len(key.Certificates) == 0 {
+		if v.AllowKeysWithoutCertificates {
+			return nil
+		}
+		return ErrCertificateMissing
+	}
+	leaf := key.Certificates[0]
+	if len(key.CertificateThumbprintSHA1) > 0 {
+		sum := sha1.Sum(leaf.Raw)
+		if !bytes.Equal(sum[:], key.CertificateThumbprintSHA1) {
+			return ErrThumbprintMismatch
+		}
+	}
+	if len(key.CertificateThumbprintSHA256) > 0 {
+		sum := sha256.Sum256(leaf.Raw)
+		if !bytes.Equal(sum[:], key.CertificateThumbprintSHA256) {
+			return ErrThumbprintMismatch
+		}
+	}
+	keyDER, err := x509.MarshalPKIXPublicKey(key.Public().Key)
+	if err != nil {
+		return err
+	}
+	leafDER, err := x509.MarshalPKIXPublicKey(leaf.PublicKey)
+	if err != nil || !bytes.Equal(keyDER, leafDER) {
+		return ErrCertificateKeyMismatch
+	}
+	if leaf.KeyUsage != 0 && leaf.KeyUsage&x509.KeyUsageDigitalSignature == 0 {
+		return ErrCertificateUsage
+	}
+	intermediates := x509.NewCertPool()
+	for _, certificate := range key.Certificates[1:] {
+		intermediates.AddCert(certificate)
+	}
+	options := x509.VerifyOptions{
+		CurrentTime:   time.Now(),
+		Intermediates: intermediates,
+		KeyUsages:     v.ExtKeyUsages,
+		Roots:         v.Roots,
+	}
+	if v.Now != nil {
+		options.CurrentTime = v.Now()
+	}
+	if len(options.KeyUsages) == 0 {
+		options.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageAny}
+	}
+	_, err = leaf.Verify(options)
+	if err != nil && v.Intermediates != nil {
+		options.Intermediates = v.Intermediates
+		_, err = leaf.Verify(options)
+	}
+	return err
+}
+
+type certificateValidatingJWKS struct {
+	keySet     JWKS
+	validation *CertificateValidation
+}
+
+// WithCertificateValidation returns a JWKS verifying tokens only with the keys of the key set
+// passing the certificate validation, keys without the x5c certificate chain are rejected unless
+// AllowKeysWithoutCertificates is set. Keys are validated when used so expired certificates
+// are rejected without reloading the key set. Key returns valid keys only.
+// Tokens without the kid header are verified with the keys without a key ID.
+func WithCertificateValidation(keySet JWKS, validation *CertificateValidation) JWKS {
+	return &certificateValidatingJWKS{keySet: keySet, validation: validation}
+}
+
+func (v *certificateValidatingJWKS) Key(kid string) []jose.JSONWebKey {
+	keys := []jose.JSONWebKey{}
+	for _, key := range v.keySet.Key(kid) {
+		if v.validation.Validate(key) == nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (v *certificateValidatingJWKS) ReadSigned(rawToken string) JWTRead {
+	token, err := jwt.ParseSigned(rawToken)
+	if err != nil {
+		return &defaultJWTRead{err: err}
+	}
+	var lastErr error = ErrSigningKeyNotKnown
+	for _, key := range v.keySet.Key(token.Headers[0].KeyID) {
+		if err := v.validation.Validate(key); err != nil {
+			lastErr = err
+			continue
+		}
+		cl := tokens.Claims{}
+		if err := token.Claims(key.Public(), &cl); err != nil {
+			lastErr = err
+			continue
+		}
+		return &defaultJWTRead{claims: cl, headers: token.Headers}
+	}
+	return &defaultJWTRead{err: lastErr}
+}
@@ -0,0 +1,122 @@
+package jwks
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+)
+
+func testCertificate(t *testing.T, template, parent *x509.Certificate, publicKey interface{}, signer *rsa.PrivateKey) *x509.Certificate {
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, publicKey, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certificate
+}
+
+func TestCertificateValidation(t *testing.T) {
+	now := time.Now()
+	rootKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "root"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	root := testCertificate(t, rootTemplate, rootTemplate, &rootKey.PublicKey, rootKey)
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+
+	signingKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "signing"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	leaf := testCertificate(t, leafTemplate, root, &signingKey.PublicKey, rootKey)
+	thumbprint := sha256.Sum256(leaf.Raw)
+
+	// round trip through JSON to exercise x5c and x5t#S256 parsing:
+	data, _ := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{
+		Key:                         &signingKey.PublicKey,
+		KeyID:                       "signing",
+		Certificates:                []*x509.Certificate{leaf, root},
+		CertificateThumbprintSHA256: thumbprint[:],
+	}}})
+	remote, err := FromJSON(data)
+	if err != nil {
+		t.Fatalf("expected JWKS but received '%v'", err)
+	}
+	validation := &CertificateValidation{Roots: roots}
+	keySet := WithCertificateValidation(remote, validation)
+	if read := keySet.ReadSigned(signTestToken(t, signingKey, jose.RS256, "signing")); read.Error() != nil {
+		t.Fatalf("expected token to verify but received '%v'", read.Error())
+	}
+
+	validation.Now = func() time.Time { return now.Add(2 * time.Hour) }
+	if read := keySet.ReadSigned(signTestToken(t, signingKey, jose.RS256, "signing")); read.Error() == nil {
+		t.Fatal("expected an expired certificate to be rejected")
+	}
+	if keys := keySet.Key("signing"); len(keys) != 0 {
+		t.Fatalf("expected no valid keys but received '%v'", keys)
+	}
+	validation.Now = nil
+
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	usageTemplate := *leafTemplate
+	usageTemplate.KeyUsage = x509.KeyUsageKeyEncipherment
+	untrustedRoot := testCertificate(t, rootTemplate, rootTemplate, &otherKey.PublicKey, otherKey)
+	for name, testCase := range map[string]struct {
+		key      jose.JSONWebKey
+		expected error
+	}{
+		"missing": {
+			key:      jose.JSONWebKey{Key: &signingKey.PublicKey},
+			expected: ErrCertificateMissing,
+		},
+		"key mismatch": {
+			key:      jose.JSONWebKey{Key: &otherKey.PublicKey, Certificates: []*x509.Certificate{leaf}},
+			expected: ErrCertificateKeyMismatch,
+		},
+		"thumbprint mismatch": {
+			key:      jose.JSONWebKey{Key: &signingKey.PublicKey, Certificates: []*x509.Certificate{leaf}, CertificateThumbprintSHA1: make([]byte, 20)},
+			expected: ErrThumbprintMismatch,
+		},
+		"usage": {
+			key:      jose.JSONWebKey{Key: &signingKey.PublicKey, Certificates: []*x509.Certificate{testCertificate(t, &usageTemplate, root, &signingKey.PublicKey, rootKey)}},
+			expected: ErrCertificateUsage,
+		},
+	} {
+		if err := validation.Validate(testCase.key); err != testCase.expected {
+			t.Fatalf("expected '%v' for '%s' but received '%v'", testCase.expected, name, err)
+		}
+	}
+
+	if err := (&CertificateValidation{AllowKeysWithoutCertificates: true}).Validate(jose.JSONWebKey{Key: &signingKey.PublicKey}); err != nil {
+		t.Fatalf("expected a key without certificates to be allowed but received '%v'", err)
+	}
+
+	untrusted := jose.JSONWebKey{Key: &signingKey.PublicKey, Certificates: []*x509.Certificate{
+		testCertificate(t, leafTemplate, untrustedRoot, &signingKey.PublicKey, otherKey),
+	}}
+	if err := validation.Validate(untrusted); err == nil {
+		t.Fatal("expected a chain with an untrusted root to be rejected")
+	}
+}
@@ -0,0 +1,109 @@
+package mtls
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"net/http"
+
+	"github.com/radekg/app-kit-tokens/tokens"
+)
+
+var (
+	// ErrCertificateBindingMismatch indicates a token bound to a different client certificate.
+	ErrCertificateBindingMismatch = errCertificateBindingMismatch()
+	// ErrClientCertificateMissing indicates a request without the TLS client certificate.
+	ErrClientCertificateMissing = errClientCertificateMissing()
+	// ErrNotCertificateBound indicates a token without the x5t#S256 confirmation where one is required.
+	ErrNotCertificateBound = errNotCertificateBound()
+)
+
+func errCertificateBindingMismatch() error {
+	return errors.New("token not bound to the client certificate")
+}
+func errClientCertificateMissing() error { return errors.New("client certificate missing") }
+func errNotCertificateBound() error      { return errors.New("token not certificate bound") }
+
+// ConfirmationMethod is the cnf member of RFC 8705 certificate-bound tokens.
+const ConfirmationMethod = "x5t#S256"
+
+// Thumbprint returns the base64url encoded SHA-256 thumbprint of the certificate.
+func Thumbprint(certificate *x509.Certificate) string {
+	sum := sha256.Sum256(certificate.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Binding represents the RFC 8705 certificate binding verification settings.
+type Binding struct {
+	// ClientCertificate: returns the client certificate of the request, optional.
+	// Defaults to the leaf certificate of the TLS connection. Set this when TLS is terminated
+	// by a proxy forwarding the client certificate.
+	ClientCertificate func(r *http.Request) (*x509.Certificate, error)
+	// Required: reject tokens which are not certificate bound.
+	Required bool
+}
+
+// Verify checks the token cnf x5t#S256 claim matches the thumbprint of the client certificate.
+// Tokens which are not certificate bound are accepted unless the binding is required.
+func (b *Binding) Verify(r *http.Request, accessToken tokens.AccessToken) error {
+	return b.verify(accessToken, func() (*x509.Certificate, error) {
+		clientCertificate := b.ClientCertificate
+		if clientCertificate == nil {
+			clientCertificate = PeerCertificate
+		}
+		return clientCertificate(r)
+	})
+}
+
+// VerifyCertificate checks the token against the client certificate obtained by the caller,
+// use it where there is no HTTP request, for example in a gRPC interceptor.
+// The ClientCertificate function is not used, a nil certificate is a missing certificate.
+func (b *Binding) VerifyCertificate(certificate *x509.Certificate, accessToken tokens.AccessToken) error {
+	return b.verify(accessToken, func() (*x509.Certificate, error) {
+		if certificate == nil {
+			return nil, ErrClientCertificateMissing
+		}
+		return certificate, nil
+	})
+}
+
+func (b *Binding) verify(accessToken tokens.AccessToken, clientCertificate func() (*x509.Certificate, error)) error {
+	cnf, _ := accessToken.Cnf()
+	bound, ok := cnf.GetClaimMustString(ConfirmationMethod)
+	if !ok || bound == "" {
+		if b.Required {
+			return ErrNotCertificateBound
+		}
+		return nil
+	}
+	certificate, err := clientCertificate()
+	if err != nil {
+		return err
+	}
+	if Thumbprint(certificate) != bound {
+		return ErrCertificateBindingMismatch
+	}
+	return nil
+}
+
+// PeerCertificate returns the leaf client certificate of the TLS connection.
+func PeerCertificate(r *http.Request) (*x509.Certificate, error) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil, ErrClientCertificateMissing
+	}
+	return r.TLS.PeerCertificates[0], nil
+}
+
+// NewHTTPClient returns an HTTP client authenticating with the client certificate,
+// use it with the mtls_endpoint_aliases endpoints. The system pool is used if roots is nil.
+func NewHTTPClient(certificate tls.Certificate, roots *x509.CertPool) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+		RootCAs:      roots,
+	}
+	return &http.Client{Transport: transport}
+}
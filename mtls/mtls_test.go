@@ -0,0 +1,85 @@
+package mtls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/radekg/app-kit-tokens/tokens"
+)
+
+func testCertificate(t *testing.T, commonName string) *x509.Certificate {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certificate
+}
+
+func TestBindingVerify(t *testing.T) {
+	client := testCertificate(t, "client")
+	other := testCertificate(t, "other")
+	bound := tokens.DefaultAccessToken(tokens.Claims{
+		"cnf": map[string]interface{}{ConfirmationMethod: Thumbprint(client)},
+	})
+	unbound := tokens.DefaultAccessToken(tokens.Claims{"sub": "subject"})
+
+	request := httptest.NewRequest(http.MethodGet, "https://api.example.com/accounts", nil)
+	request.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{client}}
+	binding := &Binding{}
+	if err := binding.Verify(request, bound); err != nil {
+		t.Fatalf("expected the bound token to verify but received '%v'", err)
+	}
+	if err := binding.Verify(request, unbound); err != nil {
+		t.Fatalf("expected the unbound token to be accepted but received '%v'", err)
+	}
+	if err := (&Binding{Required: true}).Verify(request, unbound); err != ErrNotCertificateBound {
+		t.Fatalf("expected ErrNotCertificateBound but received '%v'", err)
+	}
+
+	request.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{other}}
+	if err := binding.Verify(request, bound); err != ErrCertificateBindingMismatch {
+		t.Fatalf("expected ErrCertificateBindingMismatch but received '%v'", err)
+	}
+	request.TLS = nil
+	if err := binding.Verify(request, bound); err != ErrClientCertificateMissing {
+		t.Fatalf("expected ErrClientCertificateMissing but received '%v'", err)
+	}
+	forwarded := &Binding{ClientCertificate: func(r *http.Request) (*x509.Certificate, error) { return client, nil }}
+	if err := forwarded.Verify(request, bound); err != nil {
+		t.Fatalf("expected the forwarded certificate to verify but received '%v'", err)
+	}
+
+	if err := binding.VerifyCertificate(client, bound); err != nil {
+		t.Fatalf("expected the bound token to verify but received '%v'", err)
+	}
+	if err := binding.VerifyCertificate(other, bound); err != ErrCertificateBindingMismatch {
+		t.Fatalf("expected ErrCertificateBindingMismatch but received '%v'", err)
+	}
+	if err := binding.VerifyCertificate(nil, bound); err != ErrClientCertificateMissing {
+		t.Fatalf("expected ErrClientCertificateMissing but received '%v'", err)
+	}
+}
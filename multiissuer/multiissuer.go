@@ -0,0 +1,431 @@
+package multiissuer
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/radekg/app-kit-tokens/jwks"
+	"github.com/radekg/app-kit-tokens/tokens"
+	"github.com/radekg/app-kit-tokens/webfinger"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+var (
+	// ErrDiscoveryRateLimited indicates a token of an issuer which is not cached while the discovery
+	// rate limit is exhausted.
+	ErrDiscoveryRateLimited = errDiscoveryRateLimited()
+	// ErrInvalidIssuerPattern indicates an allow-list entry which is not an absolute URL
+	// or which has a wildcard outside of the path.
+	ErrInvalidIssuerPattern = errInvalidIssuerPattern()
+	// ErrIssuerMismatch indicates a discovery document advertising a different issuer than requested.
+	ErrIssuerMismatch = errIssuerMismatch()
+	// ErrIssuerNotAllowed indicates a token issued by an issuer not in the allow-list.
+	ErrIssuerNotAllowed = errIssuerNotAllowed()
+	// ErrJWKSHostMismatch indicates a discovery document with jwks_uri on a different host than the issuer.
+	ErrJWKSHostMismatch = errJWKSHostMismatch()
+)
+
+func errDiscoveryRateLimited() error { return errors.New("issuer discovery rate limited") }
+func errInvalidIssuerPattern() error { return errors.New("invalid issuer pattern") }
+func errIssuerMismatch() error       { return errors.New("discovered issuer mismatch") }
+func errIssuerNotAllowed() error     { return errors.New("issuer not allowed") }
+func errJWKSHostMismatch() error     { return errors.New("jwks_uri host does not match the issuer host") }
+
+const (
+	defaultFailureBackoff          = 10 * time.Second
+	defaultMaxDiscoveriesPerMinute = 10
+	defaultMaxIssuers              = 100
+	defaultMinRefreshInterval      = time.Minute
+	defaultTTL                     = time.Hour
+)
+
+// Config represents the multi-issuer verifier configuration.
+type Config struct {
+	// AllowCrossHostJWKS: accept discovery documents with jwks_uri on a different host than the issuer.
+	// Required for some providers, for example Google.
+	AllowCrossHostJWKS bool
+	// FailureBackoff: how long a failed discovery is cached before the issuer is discovered again,
+	// doubled with every consecutive failure up to the TTL, defaults to ten seconds.
+	FailureBackoff time.Duration
+	// HTTPClient: the client used for discovery, a default client is used if not set.
+	HTTPClient *http.Client
+	// Issuers: the allowed issuers. An entry is either an exact issuer URL or a pattern
+	// where * matches a single path segment, for example: https://sso.example.com/auth/realms/*.
+	Issuers []string
+	// MaxDiscoveriesPerMinute: the maximum number of discoveries of issuers which are not cached,
+	// across all issuers, tokens of other issuers are rejected until the minute passes, defaults to 10.
+	MaxDiscoveriesPerMinute int
+	// MaxIssuers: the maximum number of cached issuers, the least recently used issuer is evicted
+	// when a new issuer is discovered, defaults to 100. Failed discoveries are cached separately,
+	// up to the same number of issuers.
+	MaxIssuers int
+	// MinRefreshInterval: the minimum age of a key set before a token with an unknown kid
+	// causes the key set to be fetched again, defaults to one minute.
+	MinRefreshInterval time.Duration
+	// TTL: how long discovered key sets are cached, defaults to one hour.
+	TTL time.Duration
+}
+
+type issuerEntry struct {
+	// refreshLock serializes refreshes of the key set:
+	refreshLock sync.Mutex
+	failure     failure
+	// fetchedAt, keySet and usedAt are written with the verifier lock held:
+	fetchedAt time.Time
+	keySet    jwks.JWKS
+	usedAt    time.Time
+}
+
+type failure struct {
+	count     int
+	lastError error
+	retryAt   time.Time
+}
+
+type verifier struct {
+	sync.Mutex
+	config      *Config
+	discoveries []time.Time
+	entries     map[string]*issuerEntry
+	failed      map[string]*failure
+	now         func() time.Time
+	patterns    [][]string
+}
+
+// New returns a JWKS verifying tokens of any of the allowed issuers.
+//
+// The issuer is read from the unverified iss claim and checked against the allow-list before
+// anything is fetched, the OpenID configuration and the JWKS of the issuer are then discovered
+// and cached. The token is verified with the key set of its issuer only.
+// Only issuers discovered successfully are cached, failed discoveries are retried with a backoff
+// and discoveries of new issuers are rate limited, so tokens with made-up issuers matching
+// a pattern cannot evict the known issuers or flood the identity provider with requests.
+// Key returns keys of the issuers discovered so far.
+func New(config *Config) (jwks.JWKS, error) {
+	patterns := [][]string{}
+	for _, issuer := range config.Issuers {
+		pattern, err := parsePattern(issuer)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+	return &verifier{
+		config:   config,
+		entries:  map[string]*issuerEntry{},
+		failed:   map[string]*failure{},
+		now:      time.Now,
+		patterns: patterns,
+	}, nil
+}
+
+// parsePattern splits the pattern into URL segments, wildcards are allowed in the path only.
+func parsePattern(issuer string) ([]string, error) {
+	parsed, err := url.Parse(strings.Replace(issuer, "*", "wildcard", -1))
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" || parsed.User != nil ||
+		parsed.RawQuery != "" || parsed.Fragment != "" {
+		return nil, ErrInvalidIssuerPattern
+	}
+	segments := strings.Split(issuer, "/")
+	for index, segment := range segments {
+		if strings.Contains(segment, "*") && (index < 3 || segment != "*") {
+			return nil, ErrInvalidIssuerPattern
+		}
+	}
+	return segments, nil
+}
+
+func (v *verifier) allowed(issuer string) bool {
+	parsed, err := url.Parse(issuer)
+	if err != nil || parsed.User != nil || parsed.RawQuery != "" || parsed.Fragment != "" || parsed.ForceQuery {
+		return false
+	}
+	segments := strings.Split(issuer, "/")
+	for _, pattern := range v.patterns {
+		if matchSegments(pattern, segments) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) != len(segments) {
+		return false
+	}
+	for index, segment := range pattern {
+		if segment == "*" {
+			value := segments[index]
+			if value == "" || value == "." || value == ".." || strings.ContainsAny(value, "?#%@\\") {
+				return false
+			}
+			continue
+		}
+		if segment != segments[index] {
+			return false
+		}
+	}
+	return true
+}
+
+func (v *verifier) Key(kid string) []jose.JSONWebKey {
+	v.Lock()
+	keySets := []jwks.JWKS{}
+	for _, entry := range v.entries {
+		keySets = append(keySets, entry.keySet)
+	}
+	v.Unlock()
+	keys := []jose.JSONWebKey{}
+	for _, keySet := range keySets {
+		keys = append(keys, keySet.Key(kid)...)
+	}
+	return keys
+}
+
+func (v *verifier) ReadSigned(rawToken string) jwks.JWTRead {
+	token, err := jwt.ParseSigned(rawToken)
+	if err != nil {
+		return &errorRead{err: err}
+	}
+	unverified := struct {
+		Issuer string `json:"iss"`
+	}{}
+	if err := token.UnsafeClaimsWithoutVerification(&unverified); err != nil {
+		return &errorRead{err: err}
+	}
+	if !v.allowed(unverified.Issuer) {
+		return &errorRead{err: ErrIssuerNotAllowed}
+	}
+
+	entry, err := v.entry(unverified.Issuer)
+	if err != nil {
+		return &errorRead{err: err}
+	}
+	entry.refreshLock.Lock()
+	defer entry.refreshLock.Unlock()
+	if v.now().Sub(entry.fetchedAt) > v.ttl() {
+		if err := v.refreshWithBackoff(unverified.Issuer, entry); err != nil {
+			return &errorRead{err: err}
+		}
+	}
+	read := entry.keySet.ReadSigned(rawToken)
+	if read.Error() == jwks.ErrSigningKeyNotKnown && v.now().Sub(entry.fetchedAt) > v.minRefreshInterval() {
+		// the issuer might have rotated the keys:
+		if err := v.refreshWithBackoff(unverified.Issuer, entry); err != nil {
+			return &errorRead{err: err}
+		}
+		read = entry.keySet.ReadSigned(rawToken)
+	}
+	if read.Error() != nil {
+		return read
+	}
+	return &issuerRead{JWTRead: read, issuer: unverified.Issuer}
+}
+
+// entry returns the cache entry of the issuer, discovering the issuer if it is not cached.
+// The entry is cached only if the discovery succeeds, the least recently used entry is evicted
+// when the cache is full.
+func (v *verifier) entry(issuer string) (*issuerEntry, error) {
+	v.Lock()
+	if entry, ok := v.entries[issuer]; ok {
+		entry.usedAt = v.now()
+		v.Unlock()
+		return entry, nil
+	}
+	if failed, ok := v.failed[issuer]; ok && v.now().Before(failed.retryAt) {
+		v.Unlock()
+		return nil, failed.lastError
+	}
+	if !v.allowDiscovery() {
+		v.Unlock()
+		return nil, ErrDiscoveryRateLimited
+	}
+	v.Unlock()
+
+	keySet, err := v.fetch(issuer)
+
+	v.Lock()
+	defer v.Unlock()
+	if err != nil {
+		failed, ok := v.failed[issuer]
+		if !ok {
+			failed = &failure{}
+			v.evictFailed()
+			v.failed[issuer] = failed
+		}
+		v.recordFailure(failed, err)
+		return nil, err
+	}
+	delete(v.failed, issuer)
+	entry, ok := v.entries[issuer]
+	if !ok {
+		if len(v.entries) >= v.maxIssuers() {
+			var evicted string
+			var evictedAt time.Time
+			for cached, cachedEntry := range v.entries {
+				if evicted == "" || cachedEntry.usedAt.Before(evictedAt) {
+					evicted, evictedAt = cached, cachedEntry.usedAt
+				}
+			}
+			delete(v.entries, evicted)
+		}
+		entry = &issuerEntry{keySet: keySet, fetchedAt: v.now()}
+		v.entries[issuer] = entry
+	}
+	entry.usedAt = v.now()
+	return entry, nil
+}
+
+// allowDiscovery records a discovery of a new issuer unless the discoveries of the last minute
+// exhausted the rate limit, must be called with the verifier lock held.
+func (v *verifier) allowDiscovery() bool {
+	recent := v.discoveries[:0]
+	for _, discoveredAt := range v.discoveries {
+		if v.now().Sub(discoveredAt) < time.Minute {
+			recent = append(recent, discoveredAt)
+		}
+	}
+	v.discoveries = recent
+	if len(v.discoveries) >= v.maxDiscoveriesPerMinute() {
+		return false
+	}
+	v.discoveries = append(v.discoveries, v.now())
+	return true
+}
+
+// evictFailed makes room for a failed discovery by removing the failure retried soonest,
+// must be called with the verifier lock held.
+func (v *verifier) evictFailed() {
+	if len(v.failed) < v.maxIssuers() {
+		return
+	}
+	var evicted string
+	var evictedAt time.Time
+	for issuer, failed := range v.failed {
+		if evicted == "" || failed.retryAt.Before(evictedAt) {
+			evicted, evictedAt = issuer, failed.retryAt
+		}
+	}
+	delete(v.failed, evicted)
+}
+
+// recordFailure caches the error until the backoff, doubled with every consecutive failure, passes.
+func (v *verifier) recordFailure(failed *failure, err error) {
+	backoff := v.failureBackoff()
+	for i := 0; i < failed.count && backoff < v.ttl(); i++ {
+		backoff = backoff * 2
+	}
+	if backoff > v.ttl() {
+		backoff = v.ttl()
+	}
+	failed.count++
+	failed.lastError, failed.retryAt = err, v.now().Add(backoff)
+}
+
+// refreshWithBackoff refreshes the entry unless a previous refresh failed recently,
+// in which case the error of the failed refresh is returned without a request.
+// Must be called with the refresh lock of the entry held.
+func (v *verifier) refreshWithBackoff(issuer string, entry *issuerEntry) error {
+	if entry.failure.lastError != nil && v.now().Before(entry.failure.retryAt) {
+		return entry.failure.lastError
+	}
+	keySet, err := v.fetch(issuer)
+	if err != nil {
+		v.recordFailure(&entry.failure, err)
+		return err
+	}
+	entry.failure = failure{}
+	v.Lock()
+	entry.keySet, entry.fetchedAt = keySet, v.now()
+	v.Unlock()
+	return nil
+}
+
+func (v *verifier) fetch(issuer string) (jwks.JWKS, error) {
+	client := v.config.HTTPClient
+	if client == nil {
+		client = &http.Client{}
+	}
+	openIDConfig, err := webfinger.ResolveOpenIDConfigurationWithHTTPClient(strings.TrimSuffix(issuer, "/"), client)
+	if err != nil {
+		return nil, err
+	}
+	if openIDConfig.Issuer() != issuer {
+		return nil, ErrIssuerMismatch
+	}
+	if !v.config.AllowCrossHostJWKS {
+		issuerURL, _ := url.Parse(issuer)
+		jwksURL, err := url.Parse(openIDConfig.JWKSURI())
+		if err != nil || jwksURL.Host != issuerURL.Host || jwksURL.Scheme != issuerURL.Scheme {
+			return nil, ErrJWKSHostMismatch
+		}
+	}
+	return openIDConfig.ResolveJWKS()
+}
+
+func (v *verifier) failureBackoff() time.Duration {
+	if v.config.FailureBackoff > 0 {
+		return v.config.FailureBackoff
+	}
+	return defaultFailureBackoff
+}
+
+func (v *verifier) maxDiscoveriesPerMinute() int {
+	if v.config.MaxDiscoveriesPerMinute > 0 {
+		return v.config.MaxDiscoveriesPerMinute
+	}
+	return defaultMaxDiscoveriesPerMinute
+}
+
+func (v *verifier) maxIssuers() int {
+	if v.config.MaxIssuers > 0 {
+		return v.config.MaxIssuers
+	}
+	return defaultMaxIssuers
+}
+
+func (v *verifier) minRefreshInterval() time.Duration {
+	if v.config.MinRefreshInterval > 0 {
+		return v.config.MinRefreshInterval
+	}
+	return defaultMinRefreshInterval
+}
+
+func (v *verifier) ttl() time.Duration {
+	if v.config.TTL > 0 {
+		return v.config.TTL
+	}
+	return defaultTTL
+}
+
+type issuerRead struct {
+	jwks.JWTRead
+	issuer string
+}
+
+// Source returns the issuer whose key set verified the token.
+func (r *issuerRead) Source() string {
+	return r.issuer
+}
+
+type errorRead struct {
+	err error
+}
+
+func (r *errorRead) Error() error {
+	return r.err
+}
+func (r *errorRead) Headers() []jose.Header {
+	return nil
+}
+func (r *errorRead) Claims() tokens.Claims {
+	return nil
+}
+func (r *errorRead) Source() string {
+	return ""
+}
@@ -0,0 +1,252 @@
+package multiissuer
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+type testIssuer struct {
+	requests int
+	server   *httptest.Server
+	signers  map[string]jose.Signer
+	sets     map[string]jose.JSONWebKeySet
+}
+
+// newTestIssuer serves a discovery document and a key set for every realm under /realms/.
+func newTestIssuer(t *testing.T, realms ...string) *testIssuer {
+	issuer := &testIssuer{signers: map[string]jose.Signer{}, sets: map[string]jose.JSONWebKeySet{}}
+	for _, realm := range realms {
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		issuer.sets[realm] = jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &privateKey.PublicKey, KeyID: realm, Algorithm: string(jose.RS256), Use: "sig"},
+		}}
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: privateKey},
+			(&jose.SignerOptions{}).WithHeader("kid", realm).WithType("JWT"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		issuer.signers[realm] = signer
+	}
+	issuer.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		issuer.requests++
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/realms/"), "/")
+		set, ok := issuer.sets[parts[0]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/.well-known/openid-configuration") {
+			json.NewEncoder(w).Encode(map[string]string{
+				"issuer":   issuer.server.URL + "/realms/" + parts[0],
+				"jwks_uri": issuer.server.URL + "/realms/" + parts[0] + "/certs",
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(set)
+	}))
+	return issuer
+}
+
+func (i *testIssuer) token(t *testing.T, realm, iss string) string {
+	rawToken, err := jwt.Signed(i.signers[realm]).Claims(map[string]interface{}{
+		"iss": iss,
+		"sub": "subject",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rawToken
+}
+
+func TestMultiIssuer(t *testing.T) {
+	issuer := newTestIssuer(t, "a", "b")
+	defer issuer.server.Close()
+	attacker := newTestIssuer(t, "a")
+	defer attacker.server.Close()
+
+	keySet, err := New(&Config{Issuers: []string{issuer.server.URL + "/realms/*"}})
+	if err != nil {
+		t.Fatalf("expected multi-issuer JWKS but received '%v'", err)
+	}
+
+	for _, realm := range []string{"a", "b", "a"} {
+		read := keySet.ReadSigned(issuer.token(t, realm, issuer.server.URL+"/realms/"+realm))
+		if read.Error() != nil {
+			t.Fatalf("expected token of realm '%s' to verify but received '%v'", realm, read.Error())
+		}
+		if read.Source() != issuer.server.URL+"/realms/"+realm {
+			t.Fatalf("expected the issuer as the source but received '%s'", read.Source())
+		}
+	}
+	if issuer.requests != 4 {
+		t.Fatalf("expected discovery results to be cached but received '%d' requests", issuer.requests)
+	}
+	if keys := keySet.Key("b"); len(keys) != 1 {
+		t.Fatalf("expected the discovered key but received '%v'", keys)
+	}
+
+	// a token signed by realm b claiming to be issued by realm a:
+	if read := keySet.ReadSigned(issuer.token(t, "b", issuer.server.URL+"/realms/a")); read.Error() == nil {
+		t.Fatal("expected the token to be verified with the key set of its issuer only")
+	}
+
+	for _, iss := range []string{
+		attacker.server.URL + "/realms/a",
+		issuer.server.URL + "/realms/a/nested",
+		issuer.server.URL + "/realms/..",
+		issuer.server.URL + "/realms/a?x=1",
+		strings.Replace(issuer.server.URL, "http://", "http://user@", 1) + "/realms/a",
+	} {
+		if read := keySet.ReadSigned(attacker.token(t, "a", iss)); read.Error() != ErrIssuerNotAllowed {
+			t.Fatalf("expected issuer '%s' to be rejected but received '%v'", iss, read.Error())
+		}
+	}
+	if attacker.requests != 0 {
+		t.Fatalf("expected no requests to a not allowed issuer but received '%d'", attacker.requests)
+	}
+}
+
+func TestMultiIssuerDiscoveredIssuerMismatch(t *testing.T) {
+	issuer := newTestIssuer(t, "a")
+	defer issuer.server.Close()
+	keySet, _ := New(&Config{Issuers: []string{issuer.server.URL + "/realms/a/"}})
+	if read := keySet.ReadSigned(issuer.token(t, "a", issuer.server.URL+"/realms/a/")); read.Error() != ErrIssuerMismatch {
+		t.Fatalf("expected ErrIssuerMismatch but received '%v'", read.Error())
+	}
+}
+
+func TestInvalidIssuerPatterns(t *testing.T) {
+	for _, pattern := range []string{
+		"https://*.example.com/realms/a",
+		"https://sso.example.com/realms/a*",
+		"/realms/a",
+		"https://sso.example.com/realms/a?x=*",
+	} {
+		if _, err := New(&Config{Issuers: []string{pattern}}); err != ErrInvalidIssuerPattern {
+			t.Fatalf("expected ErrInvalidIssuerPattern for '%s' but received '%v'", pattern, err)
+		}
+	}
+}
+
+func TestMultiIssuerFailureBackoff(t *testing.T) {
+	issuer := newTestIssuer(t, "a")
+	defer issuer.server.Close()
+	keySet, _ := New(&Config{Issuers: []string{issuer.server.URL + "/realms/*"}, FailureBackoff: time.Minute})
+	now := time.Now()
+	keySet.(*verifier).now = func() time.Time { return now }
+
+	unknown := issuer.token(t, "a", issuer.server.URL+"/realms/unknown")
+	for i := 0; i < 3; i++ {
+		if read := keySet.ReadSigned(unknown); read.Error() == nil {
+			t.Fatal("expected the discovery of an unknown realm to fail")
+		}
+	}
+	if issuer.requests != 1 {
+		t.Fatalf("expected the failed discovery to be cached but received '%d' requests", issuer.requests)
+	}
+	now = now.Add(time.Minute + time.Second)
+	keySet.ReadSigned(unknown)
+	if issuer.requests != 2 {
+		t.Fatalf("expected the discovery to be retried after the backoff but received '%d' requests", issuer.requests)
+	}
+	now = now.Add(time.Minute + time.Second)
+	keySet.ReadSigned(unknown)
+	if issuer.requests != 2 {
+		t.Fatalf("expected the backoff to double after consecutive failures but received '%d' requests", issuer.requests)
+	}
+}
+
+func TestMultiIssuerMaxIssuers(t *testing.T) {
+	issuer := newTestIssuer(t, "a", "b", "c")
+	defer issuer.server.Close()
+	keySet, _ := New(&Config{Issuers: []string{issuer.server.URL + "/realms/*"}, MaxIssuers: 2})
+	now := time.Now()
+	keySet.(*verifier).now = func() time.Time { return now }
+
+	for _, realm := range []string{"a", "b", "a", "c"} {
+		now = now.Add(time.Second)
+		if read := keySet.ReadSigned(issuer.token(t, realm, issuer.server.URL+"/realms/"+realm)); read.Error() != nil {
+			t.Fatalf("expected token of realm '%s' to verify but received '%v'", realm, read.Error())
+		}
+	}
+	entries := keySet.(*verifier).entries
+	if len(entries) != 2 {
+		t.Fatalf("expected the cache to be bounded but received '%d' entries", len(entries))
+	}
+	if _, ok := entries[issuer.server.URL+"/realms/b"]; ok {
+		t.Fatal("expected the least recently used issuer to be evicted")
+	}
+}
+
+func TestMultiIssuerMadeUpIssuers(t *testing.T) {
+	issuer := newTestIssuer(t, "a")
+	defer issuer.server.Close()
+	keySet, _ := New(&Config{Issuers: []string{issuer.server.URL + "/realms/*"}, MaxDiscoveriesPerMinute: 3, MaxIssuers: 1})
+	now := time.Now()
+	keySet.(*verifier).now = func() time.Time { return now }
+
+	if read := keySet.ReadSigned(issuer.token(t, "a", issuer.server.URL+"/realms/a")); read.Error() != nil {
+		t.Fatalf("expected token of realm 'a' to verify but received '%v'", read.Error())
+	}
+	for _, realm := range []string{"x1", "x2", "x3", "x4"} {
+		keySet.ReadSigned(issuer.token(t, "a", issuer.server.URL+"/realms/"+realm))
+	}
+	if issuer.requests != 4 {
+		t.Fatalf("expected the discovery of new issuers to be rate limited but received '%d' requests", issuer.requests)
+	}
+	if read := keySet.ReadSigned(issuer.token(t, "a", issuer.server.URL+"/realms/x5")); read.Error() != ErrDiscoveryRateLimited {
+		t.Fatalf("expected ErrDiscoveryRateLimited but received '%v'", read.Error())
+	}
+	if failed := keySet.(*verifier).failed; len(failed) != 1 {
+		t.Fatalf("expected the failed discoveries to be bounded but received '%d' entries", len(failed))
+	}
+	if _, ok := keySet.(*verifier).entries[issuer.server.URL+"/realms/a"]; !ok {
+		t.Fatal("expected failed discoveries not to evict the known issuer")
+	}
+	if read := keySet.ReadSigned(issuer.token(t, "a", issuer.server.URL+"/realms/a")); read.Error() != nil {
+		t.Fatalf("expected token of realm 'a' to verify but received '%v'", read.Error())
+	}
+
+	now = now.Add(time.Minute)
+	if read := keySet.ReadSigned(issuer.token(t, "a", issuer.server.URL+"/realms/x5")); read.Error() == ErrDiscoveryRateLimited {
+		t.Fatal("expected the rate limit to reset after a minute")
+	}
+}
+
+func TestMultiIssuerKeyDuringRefresh(t *testing.T) {
+	issuer := newTestIssuer(t, "a")
+	defer issuer.server.Close()
+	keySet, _ := New(&Config{Issuers: []string{issuer.server.URL + "/realms/*"}})
+	if read := keySet.ReadSigned(issuer.token(t, "a", issuer.server.URL+"/realms/a")); read.Error() != nil {
+		t.Fatalf("expected token of realm 'a' to verify but received '%v'", read.Error())
+	}
+
+	entry := keySet.(*verifier).entries[issuer.server.URL+"/realms/a"]
+	entry.refreshLock.Lock()
+	defer entry.refreshLock.Unlock()
+	keys := make(chan []jose.JSONWebKey, 1)
+	go func() {
+		keys <- keySet.Key("a")
+	}()
+	select {
+	case received := <-keys:
+		if len(received) != 1 {
+			t.Fatalf("expected the cached key but received '%v'", received)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected Key not to wait for a refresh in progress")
+	}
+}
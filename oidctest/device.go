@@ -0,0 +1,131 @@
+package oidctest
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/radekg/app-kit-tokens/tokens"
+)
+
+const (
+	grantTypeDeviceCode = "urn:ietf:params:oauth:grant-type:device_code"
+	// userCodeCharacters excludes the vowels and the characters easily confused, RFC 8628 section 6.1.
+	userCodeCharacters = "BCDFGHJKLMNPQRSTVWXZ"
+	deviceExpiresIn    = 10 * time.Minute
+)
+
+type deviceStatus int
+
+const (
+	devicePending deviceStatus = iota
+	deviceApproved
+	deviceDenied
+)
+
+type device struct {
+	clientID  string
+	expiresAt time.Time
+	scopes    tokens.Scopes
+	status    deviceStatus
+	userCode  string
+	username  string
+}
+
+func (p *Provider) decideDevice(userCode, username string, status deviceStatus) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, pending := range p.devices {
+		if pending.userCode == userCode && pending.status == devicePending && time.Now().Before(pending.expiresAt) {
+			pending.status, pending.username = status, username
+			return true
+		}
+	}
+	return false
+}
+
+// deviceAuthorization issues the device and user codes, RFC 8628.
+func (p *Provider) deviceAuthorization(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request")
+		return
+	}
+	clientID, ok := p.authenticateClient(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+	deviceCode, err := randomCode()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+	userCode, err := randomUserCode()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+	p.mu.Lock()
+	p.devices[deviceCode] = &device{
+		clientID:  clientID,
+		expiresAt: time.Now().Add(deviceExpiresIn),
+		scopes:    tokens.NewScopes(strings.Fields(r.PostForm.Get("scope"))...),
+		userCode:  userCode,
+	}
+	p.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"device_code":               deviceCode,
+		"expires_in":                int64(deviceExpiresIn.Seconds()),
+		"interval":                  5,
+		"user_code":                 userCode,
+		"verification_uri":          p.URL("/device"),
+		"verification_uri_complete": p.URL("/device?user_code=" + userCode),
+	})
+}
+
+// deviceGrant returns the username and scopes of an approved device code.
+// The device code is single use, the error code is returned when the code is not approved.
+func (p *Provider) deviceGrant(deviceCode, clientID string) (string, tokens.Scopes, string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pending, ok := p.devices[deviceCode]
+	if !ok || pending.clientID != clientID {
+		return "", nil, "invalid_grant"
+	}
+	if time.Now().After(pending.expiresAt) {
+		delete(p.devices, deviceCode)
+		return "", nil, "expired_token"
+	}
+	switch pending.status {
+	case deviceApproved:
+		delete(p.devices, deviceCode)
+		return pending.username, pending.scopes, ""
+	case deviceDenied:
+		delete(p.devices, deviceCode)
+		return "", nil, "access_denied"
+	default:
+		return "", nil, "authorization_pending"
+	}
+}
+
+func randomCode() (string, error) {
+	value := make([]byte, 32)
+	if _, err := rand.Read(value); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(value), nil
+}
+
+func randomUserCode() (string, error) {
+	value := make([]byte, 8)
+	if _, err := rand.Read(value); err != nil {
+		return "", err
+	}
+	code := make([]byte, len(value))
+	for i, b := range value {
+		code[i] = userCodeCharacters[int(b)%len(userCodeCharacters)]
+	}
+	return string(code[:4]) + "-" + string(code[4:]), nil
+}
@@ -0,0 +1,318 @@
+package oidctest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/radekg/app-kit-tokens/bearer"
+	"github.com/radekg/app-kit-tokens/jwks"
+	"github.com/radekg/app-kit-tokens/signer"
+	"github.com/radekg/app-kit-tokens/tokens"
+	"github.com/radekg/app-kit-tokens/webfinger"
+)
+
+const (
+	grantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	tokenTypeAccessToken   = "urn:ietf:params:oauth:token-type:access_token"
+)
+
+func (p *Provider) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(PathOpenIDConfiguration, func(w http.ResponseWriter, r *http.Request) {
+		webfinger.OpenIDConfigurationHandler(p.metadata(), 0).ServeHTTP(w, r)
+	})
+	mux.HandleFunc(PathUMA2Configuration, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"grant_types_supported":                 []string{"client_credentials", "password", "refresh_token", grantTypeDeviceCode, grantTypeTokenExchange},
+			"introspection_endpoint":                p.URL(PathIntrospection),
+			"issuer":                                p.Issuer(),
+			"jwks_uri":                              p.URL(PathJWKS),
+			"token_endpoint":                        p.URL(PathToken),
+			"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+		})
+	})
+	mux.Handle(PathJWKS, jwks.Handler(p.verificationKeys, 0))
+	mux.HandleFunc(PathToken, p.token)
+	mux.HandleFunc(PathDeviceAuthorization, p.deviceAuthorization)
+	mux.HandleFunc(PathIntrospection, p.introspect)
+	mux.HandleFunc(PathRevocation, p.revoke)
+	mux.HandleFunc(PathUserInfo, p.userInfo)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p.fail(w, r.URL.Path) {
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (p *Provider) metadata() *webfinger.OpenIDMetadata {
+	return &webfinger.OpenIDMetadata{
+		DeviceAuthorizationEndpointValue:       p.URL(PathDeviceAuthorization),
+		GrantTypesSupportedValue:               []string{"client_credentials", "password", "refresh_token", grantTypeDeviceCode, grantTypeTokenExchange},
+		IDTokenSigningAlgValuesSupportedValue:  p.signingAlgorithms(),
+		IntrospectionEndpointValue:             p.URL(PathIntrospection),
+		IssuerValue:                            p.Issuer(),
+		JWKSURIValue:                           p.URL(PathJWKS),
+		ResponseTypesSupportedValue:            []string{"code", "id_token", "token id_token"},
+		ScopesSupportedValue:                   []string{"openid", "offline_access"},
+		SubjectTypesSupportedValue:             []string{"public"},
+		TokenEndpointValue:                     p.URL(PathToken),
+		TokenEndpointAuthMethodsSupportedValue: []string{"client_secret_basic", "client_secret_post"},
+		TokenIntrospectionEndpointValue:        p.URL(PathIntrospection),
+		UserInfoEndpointValue:                  p.URL(PathUserInfo),
+	}
+}
+
+func (p *Provider) fail(w http.ResponseWriter, path string) bool {
+	p.mu.Lock()
+	failures := p.failures[path]
+	if len(failures) == 0 {
+		p.mu.Unlock()
+		return false
+	}
+	p.failures[path] = failures[1:]
+	p.mu.Unlock()
+	writeError(w, failures[0].status, failures[0].code)
+	return true
+}
+
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request")
+		return
+	}
+	clientID, ok := p.authenticateClient(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+	scopes := tokens.NewScopes(strings.Fields(r.PostForm.Get("scope"))...)
+	subject, userClaims := clientID, tokens.Claims{}
+	switch r.PostForm.Get("grant_type") {
+	case "client_credentials":
+	case grantTypeTokenExchange:
+		p.exchange(w, r, clientID, scopes)
+		return
+	case grantTypeDeviceCode:
+		username, deviceScopes, code := p.deviceGrant(r.PostForm.Get("device_code"), clientID)
+		if code != "" {
+			writeError(w, http.StatusBadRequest, code)
+			return
+		}
+		subject, scopes = username, deviceScopes
+		if user, ok := p.config.Users[username]; ok {
+			userClaims = user.Claims
+		}
+	case "password":
+		user, ok := p.config.Users[r.PostForm.Get("username")]
+		if !ok || user.Password != r.PostForm.Get("password") {
+			writeError(w, http.StatusBadRequest, "invalid_grant")
+			return
+		}
+		subject, userClaims = r.PostForm.Get("username"), user.Claims
+	case "refresh_token":
+		claims, ok := p.verify(r.PostForm.Get("refresh_token"))
+		typ, _ := claims.GetClaimMustString("typ")
+		issuedTo, _ := claims.GetClaimMustString("client_id")
+		// RFC 6749 section 6: the refresh token must be issued to the authenticated client:
+		if !ok || typ != "Refresh" || issuedTo != clientID {
+			writeError(w, http.StatusBadRequest, "invalid_grant")
+			return
+		}
+		subject, _ = claims.GetClaimMustString("sub")
+		scopes, _ = tokens.ParseScopes(claims["scope"])
+		if user, ok := p.config.Users[subject]; ok {
+			userClaims = user.Claims
+		}
+	default:
+		writeError(w, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+	if sub, ok := userClaims.GetClaimMustString("sub"); ok {
+		subject = sub
+	}
+
+	accessToken, err := p.sign(signer.NewAccessToken(subject).ClientID(clientID).Scopes(scopes...), nil, 0)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+	response := map[string]interface{}{
+		"access_token": accessToken,
+		"expires_in":   int64(p.expiresIn().Seconds()),
+		"token_type":   "Bearer",
+		"scope":        scopes.String(),
+	}
+	if r.PostForm.Get("grant_type") != "client_credentials" {
+		refreshToken, err := p.sign(signer.NewRefreshToken(subject).Scopes(scopes...).ClientID(clientID), nil, time.Hour)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error")
+			return
+		}
+		response["refresh_token"] = refreshToken
+		response["refresh_expires_in"] = int64(time.Hour.Seconds())
+	}
+	if scopes.Has("openid") {
+		idToken, err := p.sign(signer.NewIDToken(subject, clientID), userClaims, 0)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "server_error")
+			return
+		}
+		response["id_token"] = idToken
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// exchange issues an access token for the subject token, RFC 8693.
+// The actor token subject is recorded in the act claim, the act claim of the subject token is nested.
+func (p *Provider) exchange(w http.ResponseWriter, r *http.Request, clientID string, scopes tokens.Scopes) {
+	subjectClaims, ok := p.verify(r.PostForm.Get("subject_token"))
+	if !ok || r.PostForm.Get("subject_token_type") == "" {
+		writeError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+	if requested := r.PostForm.Get("requested_token_type"); requested != "" && requested != tokenTypeAccessToken {
+		writeError(w, http.StatusBadRequest, "invalid_request")
+		return
+	}
+	// the exchanged token never carries scopes the subject token does not have:
+	subjectScopes, _ := tokens.DefaultAccessToken(subjectClaims).Scopes()
+	for _, scope := range scopes {
+		if !subjectScopes.Has(scope) {
+			writeError(w, http.StatusBadRequest, "invalid_scope")
+			return
+		}
+	}
+	if len(scopes) == 0 {
+		scopes = subjectScopes
+	}
+	subject, _ := subjectClaims.GetClaimMustString("sub")
+	builder := signer.NewAccessToken(subject).ClientID(clientID).Scopes(scopes...)
+	if audience := append(r.PostForm["audience"], r.PostForm["resource"]...); len(audience) > 0 {
+		builder.Audience(audience...)
+	}
+	if actorToken := r.PostForm.Get("actor_token"); actorToken != "" {
+		actorClaims, ok := p.verify(actorToken)
+		if !ok || r.PostForm.Get("actor_token_type") == "" {
+			writeError(w, http.StatusBadRequest, "invalid_grant")
+			return
+		}
+		actorSubject, _ := actorClaims.GetClaimMustString("sub")
+		act := map[string]interface{}{"sub": actorSubject}
+		if prior, ok := subjectClaims["act"]; ok {
+			act["act"] = prior
+		}
+		builder.Claim("act", act)
+	}
+	accessToken, err := p.sign(builder, nil, 0)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token":      accessToken,
+		"expires_in":        int64(p.expiresIn().Seconds()),
+		"issued_token_type": tokenTypeAccessToken,
+		"token_type":        "Bearer",
+	})
+}
+
+func (p *Provider) introspect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request")
+		return
+	}
+	if _, ok := p.authenticateClient(r); !ok {
+		writeError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+	claims, ok := p.verify(r.PostForm.Get("token"))
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"active": false})
+		return
+	}
+	response := map[string]interface{}{"active": true}
+	for _, claim := range []string{"client_id", "exp", "iat", "iss", "jti", "nbf", "scope", "sub"} {
+		if value, ok := claims[claim]; ok {
+			response[claim] = value
+		}
+	}
+	if audience, ok := tokens.ParseAudience(claims["aud"]); ok {
+		response["aud"] = audience
+	}
+	if typ, _ := claims.GetClaimMustString("typ"); typ == "Refresh" {
+		response["token_type"] = "refresh_token"
+	} else {
+		response["token_type"] = "access_token"
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (p *Provider) revoke(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request")
+		return
+	}
+	if _, ok := p.authenticateClient(r); !ok {
+		writeError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+	// RFC 7009: invalid tokens do not cause an error response.
+	p.Revoke(r.PostForm.Get("token"))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (p *Provider) userInfo(w http.ResponseWriter, r *http.Request) {
+	rawToken, err := bearer.ExtractToken(r, nil)
+	if err != nil {
+		bearer.WriteChallenge(w, &bearer.Challenge{ErrorCode: bearer.ErrorCodeInvalidRequest})
+		return
+	}
+	claims, ok := p.verify(rawToken)
+	// only access tokens are accepted, refresh and ID tokens are not bearer credentials:
+	if typ, _ := claims.GetClaimMustString("typ"); !ok || typ != "Bearer" {
+		bearer.WriteChallenge(w, &bearer.Challenge{ErrorCode: bearer.ErrorCodeInvalidToken})
+		return
+	}
+	subject, _ := claims.GetClaimMustString("sub")
+	response := map[string]interface{}{"sub": subject}
+	for username, user := range p.config.Users {
+		sub, ok := user.Claims.GetClaimMustString("sub")
+		if !ok {
+			sub = username
+		}
+		if sub != subject {
+			continue
+		}
+		for name, value := range user.Claims {
+			response[name] = value
+		}
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// authenticateClient authenticates the client with client_secret_basic or client_secret_post.
+func (p *Provider) authenticateClient(r *http.Request) (string, bool) {
+	if err := r.ParseForm(); err != nil {
+		return "", false
+	}
+	clientID, secret, ok := r.BasicAuth()
+	if !ok {
+		clientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	expected, ok := p.clients()[clientID]
+	return clientID, ok && expected == secret
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	writeJSON(w, status, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
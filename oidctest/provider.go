@@ -0,0 +1,291 @@
+package oidctest
+
+import (
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/radekg/app-kit-tokens/jwks"
+	"github.com/radekg/app-kit-tokens/signer"
+	"github.com/radekg/app-kit-tokens/tokens"
+	"gopkg.in/square/go-jose.v2"
+)
+
+const (
+	// DefaultClientID is the client accepted when no clients are configured.
+	DefaultClientID = "test-client"
+	// DefaultClientSecret is the secret of the default client.
+	DefaultClientSecret = "test-secret"
+)
+
+// Endpoint paths served by the provider.
+const (
+	PathDeviceAuthorization = "/device_authorization"
+	PathIntrospection       = "/introspect"
+	PathJWKS                = "/jwks"
+	PathOpenIDConfiguration = "/.well-known/openid-configuration"
+	PathRevocation          = "/revoke"
+	PathToken               = "/token"
+	PathUMA2Configuration   = "/.well-known/uma2-configuration"
+	PathUserInfo            = "/userinfo"
+)
+
+// User is a resource owner able to authenticate with the password grant.
+type User struct {
+	// Claims: the claims of the user added to the ID token and returned by userinfo.
+	// The sub claim defaults to the username.
+	Claims   tokens.Claims
+	Password string
+}
+
+// Config represents the provider configuration.
+type Config struct {
+	// Algorithm: the algorithm of the generated signing keys, defaults to RS256.
+	Algorithm jose.SignatureAlgorithm
+	// Claims: additional claims of every access token, optional.
+	Claims tokens.Claims
+	// Clients: client secrets by client ID, a client with DefaultClientID and
+	// DefaultClientSecret is accepted if empty.
+	Clients map[string]string
+	// ExpiresIn: the lifetime of access and ID tokens, defaults to five minutes.
+	ExpiresIn time.Duration
+	// Keys: private signing keys with key IDs, optional. The first key is active after New,
+	// every RotateKeys call activates the next one. Keys are generated once these are used up.
+	Keys []jose.JSONWebKey
+	// Users: users by username, optional.
+	Users map[string]User
+}
+
+type failure struct {
+	code   string
+	status int
+}
+
+// Provider is an in-process OpenID provider for tests, served by an httptest.Server.
+// The issuer is the URL of the server.
+type Provider struct {
+	mu       sync.Mutex
+	config   *Config
+	devices  map[string]*device
+	failures map[string][]failure
+	keyRing  signer.KeyRing
+	keys     []jose.JSONWebKey
+	pending  []jose.JSONWebKey
+	revoked  map[string]bool
+	server   *httptest.Server
+}
+
+// New starts a provider, it must be closed with Close.
+func New(config *Config) (*Provider, error) {
+	if config == nil {
+		config = &Config{}
+	}
+	provider := &Provider{
+		config:   config,
+		devices:  map[string]*device{},
+		failures: map[string][]failure{},
+		pending:  config.Keys,
+		revoked:  map[string]bool{},
+	}
+	if err := provider.RotateKeys(true); err != nil {
+		return nil, err
+	}
+	provider.server = httptest.NewServer(provider.handler())
+	return provider, nil
+}
+
+// Close shuts the server down.
+func (p *Provider) Close() {
+	p.server.Close()
+}
+
+// Issuer returns the issuer, the URL of the server.
+func (p *Provider) Issuer() string {
+	return p.server.URL
+}
+
+// URL returns the URL of the endpoint path.
+func (p *Provider) URL(path string) string {
+	return p.server.URL + path
+}
+
+// KeySet returns the verification keys of the provider.
+func (p *Provider) KeySet() jwks.JWKS {
+	return jwks.FromKeySet(p.verificationKeys())
+}
+
+// RotateKeys activates the next configured signing key or generates a new one. The previous
+// keys stay published unless retainPrevious is false, in which case tokens signed before
+// the rotation can no longer be verified.
+func (p *Provider) RotateKeys(retainPrevious bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var key jose.JSONWebKey
+	if len(p.pending) > 0 {
+		key = p.pending[0]
+	} else {
+		generated, err := signer.GenerateKey(p.algorithm())
+		if err != nil {
+			return err
+		}
+		key = generated
+	}
+	keys := []jose.JSONWebKey{key}
+	if retainPrevious {
+		keys = append(keys, p.keys...)
+	}
+	keyRing, err := signer.NewKeyRing(key.KeyID, keys...)
+	if err != nil {
+		return err
+	}
+	if len(p.pending) > 0 {
+		p.pending = p.pending[1:]
+	}
+	p.keys, p.keyRing = keys, keyRing
+	return nil
+}
+
+// FailNext makes the next request to the endpoint path fail with the HTTP status
+// and the OAuth error code. Failures queue up when called repeatedly.
+func (p *Provider) FailNext(path string, status int, code string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failures[path] = append(p.failures[path], failure{code: code, status: status})
+}
+
+// ApproveDevice approves the device authorization of the user code on behalf of the user,
+// the next token request of the device is answered with the tokens of the user.
+// Returns false if the user code is not pending.
+func (p *Provider) ApproveDevice(userCode, username string) bool {
+	return p.decideDevice(userCode, username, deviceApproved)
+}
+
+// DenyDevice denies the device authorization of the user code,
+// the next token request of the device is answered with access_denied.
+// Returns false if the user code is not pending.
+func (p *Provider) DenyDevice(userCode string) bool {
+	return p.decideDevice(userCode, "", deviceDenied)
+}
+
+// AccessToken mints an access token for the subject with the claims added to the default ones.
+func (p *Provider) AccessToken(subject string, claims tokens.Claims) (string, error) {
+	return p.sign(signer.NewAccessToken(subject).ClientID(p.defaultClientID()), claims, 0)
+}
+
+// ExpiredAccessToken mints an access token for the subject which expired a minute ago.
+func (p *Provider) ExpiredAccessToken(subject string, claims tokens.Claims) (string, error) {
+	builder := signer.NewAccessToken(subject).
+		ClientID(p.defaultClientID()).
+		Claim("iat", time.Now().Add(-time.Hour).Unix()).
+		Claim("nbf", time.Now().Add(-time.Hour).Unix()).
+		Claim("exp", time.Now().Add(-time.Minute).Unix())
+	return p.sign(builder, claims, 0)
+}
+
+// IDToken mints an ID token for the subject and the client with the claims added to the default ones.
+func (p *Provider) IDToken(subject, clientID string, claims tokens.Claims) (string, error) {
+	return p.sign(signer.NewIDToken(subject, clientID), claims, 0)
+}
+
+// Revoke revokes the token, it is reported inactive by the introspection endpoint.
+func (p *Provider) Revoke(rawToken string) {
+	if jti, ok := p.jti(rawToken); ok {
+		p.mu.Lock()
+		p.revoked[jti] = true
+		p.mu.Unlock()
+	}
+}
+
+func (p *Provider) sign(builder *signer.Builder, claims tokens.Claims, expiresIn time.Duration) (string, error) {
+	for name, value := range p.config.Claims {
+		builder.Claim(name, value)
+	}
+	for name, value := range claims {
+		builder.Claim(name, value)
+	}
+	if expiresIn == 0 {
+		expiresIn = p.expiresIn()
+	}
+	p.mu.Lock()
+	keyRing := p.keyRing
+	p.mu.Unlock()
+	return builder.ExpiresIn(expiresIn).Sign(signer.New(keyRing, &signer.Config{Issuer: p.Issuer()}))
+}
+
+func (p *Provider) verificationKeys() jose.JSONWebKeySet {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.keyRing.VerificationKeys()
+}
+
+// signingAlgorithms returns the algorithms of the published keys.
+func (p *Provider) signingAlgorithms() []string {
+	algorithms := []string{}
+	seen := map[string]bool{}
+	for _, key := range p.verificationKeys().Keys {
+		if !seen[key.Algorithm] {
+			seen[key.Algorithm] = true
+			algorithms = append(algorithms, key.Algorithm)
+		}
+	}
+	return algorithms
+}
+
+func (p *Provider) algorithm() jose.SignatureAlgorithm {
+	if p.config.Algorithm != "" {
+		return p.config.Algorithm
+	}
+	return jose.RS256
+}
+
+func (p *Provider) expiresIn() time.Duration {
+	if p.config.ExpiresIn > 0 {
+		return p.config.ExpiresIn
+	}
+	return 5 * time.Minute
+}
+
+func (p *Provider) clients() map[string]string {
+	if len(p.config.Clients) == 0 {
+		return map[string]string{DefaultClientID: DefaultClientSecret}
+	}
+	return p.config.Clients
+}
+
+func (p *Provider) defaultClientID() string {
+	if clients := p.clients(); len(clients) == 1 {
+		for clientID := range clients {
+			return clientID
+		}
+	}
+	return DefaultClientID
+}
+
+// verify returns the claims of a valid, unexpired and not revoked token.
+func (p *Provider) verify(rawToken string) (tokens.Claims, bool) {
+	read := p.KeySet().ReadSigned(rawToken)
+	if read.Error() != nil {
+		return nil, false
+	}
+	claims := read.Claims()
+	if err := tokens.DefaultClaimsValidator(&tokens.Expectations{Issuer: p.Issuer()}).Validate(claims); err != nil {
+		return nil, false
+	}
+	if jti, ok := claims.GetClaimMustString("jti"); ok {
+		p.mu.Lock()
+		revoked := p.revoked[jti]
+		p.mu.Unlock()
+		if revoked {
+			return nil, false
+		}
+	}
+	return claims, true
+}
+
+func (p *Provider) jti(rawToken string) (string, bool) {
+	read := p.KeySet().ReadSigned(rawToken)
+	if read.Error() != nil {
+		return "", false
+	}
+	return read.Claims().GetClaimMustString("jti")
+}
@@ -0,0 +1,223 @@
+package oidctest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/radekg/app-kit-tokens/jwks"
+	"github.com/radekg/app-kit-tokens/tokens"
+	"github.com/radekg/app-kit-tokens/webfinger"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func postForm(t *testing.T, provider *Provider, path string, values url.Values) (int, map[string]interface{}) {
+	request, _ := http.NewRequest(http.MethodPost, provider.URL(path), strings.NewReader(values.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.SetBasicAuth(DefaultClientID, DefaultClientSecret)
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body := map[string]interface{}{}
+	json.NewDecoder(resp.Body).Decode(&body)
+	return resp.StatusCode, body
+}
+
+func TestDiscoveryAndKeyRotation(t *testing.T) {
+	provider, err := New(nil)
+	if err != nil {
+		t.Fatalf("expected provider but received '%v'", err)
+	}
+	defer provider.Close()
+
+	openIDConfig, err := webfinger.ResolveOpenIDConfiguration(provider.Issuer())
+	if err != nil || openIDConfig.Issuer() != provider.Issuer() || openIDConfig.TokenEndpoint() != provider.URL(PathToken) {
+		t.Fatalf("expected the OpenID configuration but received '%v', '%v'", openIDConfig, err)
+	}
+	uma2Config, err := webfinger.ResolveUMA2Configuration(provider.Issuer())
+	if err != nil || uma2Config.JWKSURI() != provider.URL(PathJWKS) {
+		t.Fatalf("expected the UMA2 configuration but received '%v', '%v'", uma2Config, err)
+	}
+
+	rawToken, _ := provider.AccessToken("subject", tokens.Claims{"scope": "read"})
+	keySet, err := openIDConfig.ResolveJWKS()
+	if err != nil {
+		t.Fatalf("expected the JWKS to resolve but received '%v'", err)
+	}
+	read := keySet.ReadSigned(rawToken)
+	if read.Error() != nil {
+		t.Fatalf("expected the token to verify but received '%v'", read.Error())
+	}
+	if iss, _ := read.Claims().GetString("iss"); iss != provider.Issuer() {
+		t.Fatalf("expected the provider issuer but received '%s'", iss)
+	}
+
+	provider.RotateKeys(false)
+	rotated, _ := provider.AccessToken("subject", nil)
+	if read := keySet.ReadSigned(rotated); read.Error() == nil {
+		t.Fatal("expected the previously resolved JWKS not to know the rotated key")
+	}
+	keySet, _ = openIDConfig.ResolveJWKS()
+	if read := keySet.ReadSigned(rawToken); read.Error() == nil {
+		t.Fatal("expected the dropped key not to be published")
+	}
+	if read := keySet.ReadSigned(rotated); read.Error() != nil {
+		t.Fatalf("expected the rotated key to be published but received '%v'", read.Error())
+	}
+}
+
+func TestPinnedKeys(t *testing.T) {
+	first, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	second, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	provider, err := New(&Config{Keys: []jose.JSONWebKey{
+		{Key: first, KeyID: "first"},
+		{Key: second, KeyID: "second"},
+	}})
+	if err != nil {
+		t.Fatalf("expected provider but received '%v'", err)
+	}
+	defer provider.Close()
+	openIDConfig, _ := webfinger.ResolveOpenIDConfiguration(provider.Issuer())
+	if algorithms := openIDConfig.IDTokenSigningAlgValuesSupported(); len(algorithms) != 1 || algorithms[0] != string(jose.ES256) {
+		t.Fatalf("expected the algorithm of the pinned key to be advertised but received '%v'", algorithms)
+	}
+	for _, key := range []jose.JSONWebKey{
+		{Key: &first.PublicKey, KeyID: "first"},
+		{Key: &second.PublicKey, KeyID: "second"},
+	} {
+		rawToken, _ := provider.AccessToken("subject", nil)
+		keySet := jwks.FromKeySet(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{key}})
+		if read := keySet.ReadSigned(rawToken); read.Error() != nil {
+			t.Fatalf("expected the token signed with the '%s' key but received '%v'", key.KeyID, read.Error())
+		}
+		provider.RotateKeys(true)
+	}
+	rawToken, _ := provider.AccessToken("subject", nil)
+	pinned := jwks.FromKeySet(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: &first.PublicKey, KeyID: "first"},
+		{Key: &second.PublicKey, KeyID: "second"},
+	}})
+	if read := pinned.ReadSigned(rawToken); read.Error() == nil {
+		t.Fatal("expected a generated key once the pinned keys are used up")
+	}
+	if read := provider.KeySet().ReadSigned(rawToken); read.Error() != nil {
+		t.Fatalf("expected the generated key to be published but received '%v'", read.Error())
+	}
+
+	if _, err := New(&Config{Keys: []jose.JSONWebKey{{Key: first}}}); err == nil {
+		t.Fatal("expected a pinned key without the key ID to be rejected")
+	}
+}
+
+func TestTokenEndpoints(t *testing.T) {
+	provider, _ := New(&Config{Users: map[string]User{
+		"alice": {Password: "secret", Claims: tokens.Claims{"sub": "user-1", "email": "alice@example.com"}},
+	}})
+	defer provider.Close()
+
+	status, body := postForm(t, provider, PathToken, url.Values{
+		"grant_type": {"password"}, "username": {"alice"}, "password": {"secret"}, "scope": {"openid profile"},
+	})
+	if status != http.StatusOK || body["id_token"] == nil || body["refresh_token"] == nil {
+		t.Fatalf("expected access, ID and refresh tokens but received '%d', '%v'", status, body)
+	}
+	rawJWT, _ := json.Marshal(body)
+	jwt, _ := tokens.DefaultJWT(rawJWT)
+
+	status, body = postForm(t, provider, PathIntrospection, url.Values{"token": {jwt.AccessToken()}})
+	introspection, _ := json.Marshal(body)
+	hydra, _ := tokens.DefaultHydraIntrospection(introspection)
+	if !hydra.Active() || hydra.Sub() != "user-1" || hydra.ClientID() != DefaultClientID || hydra.Scope() != "openid profile" {
+		t.Fatalf("expected an active introspection response but received '%d', '%v'", status, body)
+	}
+
+	request, _ := http.NewRequest(http.MethodGet, provider.URL(PathUserInfo), nil)
+	request.Header.Set("Authorization", "Bearer "+jwt.AccessToken())
+	resp, _ := http.DefaultClient.Do(request)
+	userInfo := map[string]interface{}{}
+	json.NewDecoder(resp.Body).Decode(&userInfo)
+	resp.Body.Close()
+	if userInfo["email"] != "alice@example.com" {
+		t.Fatalf("expected the user claims but received '%v'", userInfo)
+	}
+	for _, rawToken := range []string{jwt.RefreshToken(), jwt.IDToken()} {
+		request, _ := http.NewRequest(http.MethodGet, provider.URL(PathUserInfo), nil)
+		request.Header.Set("Authorization", "Bearer "+rawToken)
+		resp, _ := http.DefaultClient.Do(request)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("expected userinfo to reject tokens other than access tokens but received '%d'", resp.StatusCode)
+		}
+	}
+
+	status, body = postForm(t, provider, PathToken, url.Values{"grant_type": {"refresh_token"}, "refresh_token": {jwt.RefreshToken()}})
+	if status != http.StatusOK || body["access_token"] == nil {
+		t.Fatalf("expected a refreshed access token but received '%d', '%v'", status, body)
+	}
+
+	postForm(t, provider, PathRevocation, url.Values{"token": {jwt.AccessToken()}})
+	if _, body := postForm(t, provider, PathIntrospection, url.Values{"token": {jwt.AccessToken()}}); body["active"] != false {
+		t.Fatalf("expected the revoked token to be inactive but received '%v'", body)
+	}
+	expired, _ := provider.ExpiredAccessToken("subject", nil)
+	if _, body := postForm(t, provider, PathIntrospection, url.Values{"token": {expired}}); body["active"] != false {
+		t.Fatalf("expected the expired token to be inactive but received '%v'", body)
+	}
+
+	if status, body := postForm(t, provider, PathToken, url.Values{"grant_type": {"password"}, "username": {"alice"}, "password": {"wrong"}}); status != http.StatusBadRequest || body["error"] != "invalid_grant" {
+		t.Fatalf("expected invalid_grant but received '%d', '%v'", status, body)
+	}
+	provider.FailNext(PathToken, http.StatusServiceUnavailable, "temporarily_unavailable")
+	if status, body := postForm(t, provider, PathToken, url.Values{"grant_type": {"client_credentials"}}); status != http.StatusServiceUnavailable || body["error"] != "temporarily_unavailable" {
+		t.Fatalf("expected the simulated failure but received '%d', '%v'", status, body)
+	}
+	if status, body := postForm(t, provider, PathToken, url.Values{"grant_type": {"client_credentials"}}); status != http.StatusOK || body["refresh_token"] != nil {
+		t.Fatalf("expected a client credentials token but received '%d', '%v'", status, body)
+	}
+}
+
+func TestRefreshTokenOfOtherClient(t *testing.T) {
+	provider, _ := New(&Config{
+		Clients: map[string]string{"client-a": "secret-a", "client-b": "secret-b"},
+		Users:   map[string]User{"alice": {Password: "secret"}},
+	})
+	defer provider.Close()
+	post := func(values url.Values) (int, map[string]interface{}) {
+		resp, err := http.PostForm(provider.URL(PathToken), values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body := map[string]interface{}{}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+
+	_, body := post(url.Values{
+		"client_id": {"client-a"}, "client_secret": {"secret-a"},
+		"grant_type": {"password"}, "username": {"alice"}, "password": {"secret"},
+	})
+	refreshToken, _ := body["refresh_token"].(string)
+	if refreshToken == "" {
+		t.Fatalf("expected a refresh token but received '%v'", body)
+	}
+	if status, body := post(url.Values{
+		"client_id": {"client-b"}, "client_secret": {"secret-b"},
+		"grant_type": {"refresh_token"}, "refresh_token": {refreshToken},
+	}); status != http.StatusBadRequest || body["error"] != "invalid_grant" {
+		t.Fatalf("expected invalid_grant for the refresh token of another client but received '%d', '%v'", status, body)
+	}
+	if status, _ := post(url.Values{
+		"client_id": {"client-a"}, "client_secret": {"secret-a"},
+		"grant_type": {"refresh_token"}, "refresh_token": {refreshToken},
+	}); status != http.StatusOK {
+		t.Fatalf("expected the refresh token to be accepted from its client but received '%d'", status)
+	}
+}
@@ -0,0 +1,152 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	// ErrInvalidRule indicates a rule configuration without exactly one rule kind.
+	ErrInvalidRule = errInvalidRule()
+)
+
+func errInvalidRule() error { return errors.New("rule configuration must define exactly one rule") }
+
+// Config represents the policy configuration.
+type Config struct {
+	// Default: the rule for requests not matching any route, requests are denied if not set.
+	Default *RuleConfig `json:"default,omitempty" yaml:"default,omitempty"`
+	// Routes: the route rules, the first matching route applies.
+	Routes []*RouteConfig `json:"routes" yaml:"routes"`
+}
+
+// RouteConfig represents the configuration of a route rule.
+type RouteConfig struct {
+	// Method: the HTTP method, any method if empty.
+	Method string `json:"method,omitempty" yaml:"method,omitempty"`
+	// Path: the path pattern, see Route for the syntax.
+	Path string      `json:"path" yaml:"path"`
+	Rule *RuleConfig `json:"rule" yaml:"rule"`
+}
+
+// RuleConfig represents a rule configuration, exactly one of the fields must be set.
+type RuleConfig struct {
+	All     []*RuleConfig  `json:"all,omitempty" yaml:"all,omitempty"`
+	Allow   bool           `json:"allow,omitempty" yaml:"allow,omitempty"`
+	Any     []*RuleConfig  `json:"any,omitempty" yaml:"any,omitempty"`
+	AnyRole *AnyRoleConfig `json:"any_role,omitempty" yaml:"any_role,omitempty"`
+	Claim   *ClaimConfig   `json:"claim,omitempty" yaml:"claim,omitempty"`
+	Deny    bool           `json:"deny,omitempty" yaml:"deny,omitempty"`
+	Not     *RuleConfig    `json:"not,omitempty" yaml:"not,omitempty"`
+	Scopes  []string       `json:"scopes,omitempty" yaml:"scopes,omitempty"`
+}
+
+// AnyRoleConfig represents the AnyRole rule configuration.
+type AnyRoleConfig struct {
+	Path  string   `json:"path" yaml:"path"`
+	Roles []string `json:"roles" yaml:"roles"`
+}
+
+// ClaimConfig represents the claim rule configuration, exactly one of equals, in and matches must be set.
+type ClaimConfig struct {
+	Path    string        `json:"path" yaml:"path"`
+	Equals  interface{}   `json:"equals,omitempty" yaml:"equals,omitempty"`
+	In      []interface{} `json:"in,omitempty" yaml:"in,omitempty"`
+	Matches string        `json:"matches,omitempty" yaml:"matches,omitempty"`
+}
+
+// Compile compiles the rule configuration into a rule.
+func (c *RuleConfig) Compile() (Rule, error) {
+	if c == nil {
+		return nil, ErrInvalidRule
+	}
+	kinds := 0
+	for _, set := range []bool{len(c.All) > 0, c.Allow, len(c.Any) > 0, c.AnyRole != nil,
+		c.Claim != nil, c.Deny, c.Not != nil, len(c.Scopes) > 0} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return nil, ErrInvalidRule
+	}
+
+	switch {
+	case len(c.All) > 0:
+		rules, err := compileAll(c.All)
+		if err != nil {
+			return nil, err
+		}
+		return All(rules...), nil
+	case c.Allow:
+		return Allow(), nil
+	case len(c.Any) > 0:
+		rules, err := compileAll(c.Any)
+		if err != nil {
+			return nil, err
+		}
+		return Any(rules...), nil
+	case c.AnyRole != nil:
+		return AnyRole(c.AnyRole.Path, c.AnyRole.Roles...)
+	case c.Claim != nil:
+		return c.Claim.compile()
+	case c.Deny:
+		return Deny(), nil
+	case c.Not != nil:
+		rule, err := c.Not.Compile()
+		if err != nil {
+			return nil, err
+		}
+		return Not(rule), nil
+	default:
+		return RequireScopes(c.Scopes...), nil
+	}
+}
+
+func (c *ClaimConfig) compile() (Rule, error) {
+	switch {
+	case c.Equals != nil && c.In == nil && c.Matches == "":
+		return ClaimEquals(c.Path, c.Equals)
+	case c.Equals == nil && c.In != nil && c.Matches == "":
+		return ClaimIn(c.Path, c.In...)
+	case c.Equals == nil && c.In == nil && c.Matches != "":
+		return ClaimMatches(c.Path, c.Matches)
+	default:
+		return nil, ErrInvalidRule
+	}
+}
+
+func compileAll(configs []*RuleConfig) ([]Rule, error) {
+	rules := []Rule{}
+	for _, config := range configs {
+		rule, err := config.Compile()
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// LoadJSON loads the policy from JSON configuration.
+func LoadJSON(rawData []byte) (Policy, error) {
+	config := &Config{}
+	decoder := json.NewDecoder(bytes.NewReader(rawData))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(config); err != nil {
+		return nil, err
+	}
+	return New(config)
+}
+
+// LoadYAML loads the policy from YAML configuration.
+func LoadYAML(rawData []byte) (Policy, error) {
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(rawData, config); err != nil {
+		return nil, err
+	}
+	return New(config)
+}
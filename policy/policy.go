@@ -0,0 +1,106 @@
+package policy
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/radekg/app-kit-tokens/tokens"
+)
+
+// Route represents the rule for requests matching the method and the path pattern.
+//
+// The path pattern uses the path.Match syntax, * matches a single path segment.
+// A pattern ending with /** matches the prefix and any sub path.
+// The request path is cleaned before matching, duplicate slashes and dot segments
+// do not bypass the route.
+type Route struct {
+	// Method: the HTTP method, any method if empty.
+	Method string
+	Path   string
+	Rule   Rule
+}
+
+func (r *Route) matches(method, requestPath string) bool {
+	if r.Method != "" && !strings.EqualFold(r.Method, method) {
+		return false
+	}
+	if strings.HasSuffix(r.Path, "/**") {
+		// match the prefix pattern against the leading segments of the request path:
+		prefix := strings.TrimSuffix(r.Path, "/**")
+		segments := strings.Count(prefix, "/")
+		parts := strings.SplitN(requestPath, "/", segments+2)
+		if len(parts) < segments+1 {
+			return false
+		}
+		matched, _ := path.Match(prefix, strings.Join(parts[:segments+1], "/"))
+		return matched
+	}
+	matched, _ := path.Match(r.Path, requestPath)
+	return matched
+}
+
+// Policy authorizes requests with route rules.
+type Policy interface {
+	// Authorize evaluates the rule of the request route against the access token claims.
+	// The signature matches the bearer.Authorizer interface.
+	Authorize(r *http.Request, accessToken tokens.AccessToken) error
+	// Evaluate evaluates the rule of the route against the claims.
+	Evaluate(method, requestPath string, claims tokens.Claims) error
+}
+
+type defaultPolicy struct {
+	defaultRule Rule
+	routes      []*Route
+}
+
+// New returns a policy for the configuration.
+func New(config *Config) (Policy, error) {
+	var defaultRule Rule
+	if config.Default != nil {
+		rule, err := config.Default.Compile()
+		if err != nil {
+			return nil, fmt.Errorf("default rule: %w", err)
+		}
+		defaultRule = rule
+	}
+	routes := []*Route{}
+	for _, routeConfig := range config.Routes {
+		rule, err := routeConfig.Rule.Compile()
+		if err != nil {
+			return nil, fmt.Errorf("route %s %s: %w", routeConfig.Method, routeConfig.Path, err)
+		}
+		routes = append(routes, &Route{Method: routeConfig.Method, Path: routeConfig.Path, Rule: rule})
+	}
+	return NewWithRoutes(defaultRule, routes...)
+}
+
+// NewWithRoutes returns a policy for the routes, the first matching route applies.
+// Requests not matching any route are evaluated against the default rule,
+// or denied if the default rule is nil.
+func NewWithRoutes(defaultRule Rule, routes ...*Route) (Policy, error) {
+	for _, route := range routes {
+		if _, err := path.Match(strings.TrimSuffix(route.Path, "/**"), ""); err != nil {
+			return nil, fmt.Errorf("route %s %s: %w", route.Method, route.Path, err)
+		}
+	}
+	if defaultRule == nil {
+		defaultRule = Deny()
+	}
+	return &defaultPolicy{defaultRule: defaultRule, routes: routes}, nil
+}
+
+func (p *defaultPolicy) Authorize(r *http.Request, accessToken tokens.AccessToken) error {
+	return p.Evaluate(r.Method, r.URL.Path, accessToken.RawClaims())
+}
+
+func (p *defaultPolicy) Evaluate(method, requestPath string, claims tokens.Claims) error {
+	requestPath = path.Clean("/" + requestPath)
+	for _, route := range p.routes {
+		if route.matches(method, requestPath) {
+			return route.Rule.Evaluate(claims)
+		}
+	}
+	return p.defaultRule.Evaluate(claims)
+}
@@ -0,0 +1,159 @@
+package policy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/radekg/app-kit-tokens/bearer"
+	"github.com/radekg/app-kit-tokens/tokens"
+)
+
+var testClaims = tokens.Claims{
+	"sub":          "subject",
+	"scope":        "openid read:users",
+	"tenant":       "tenant-1",
+	"level":        float64(3),
+	"email":        "member@example.com",
+	"realm_access": map[string]interface{}{"roles": []interface{}{"offline_access", "admin"}},
+	"groups":       []interface{}{"/eu", "/us"},
+}
+
+func TestRules(t *testing.T) {
+	adminRole, _ := AnyRole("realm_access.roles", "owner", "admin")
+	tenant, _ := ClaimEquals("tenant", "tenant-1")
+	level, _ := ClaimIn("level", 2, 3)
+	group, _ := ClaimEquals("groups", "/us")
+	email, _ := ClaimMatches("email", `@example\.com$`)
+
+	for _, rule := range []Rule{
+		RequireScopes("openid", "read:users"),
+		RequireScopes("read:*"),
+		adminRole, tenant, level, group, email,
+		All(tenant, Any(Deny(), adminRole)),
+		Not(RequireScopes("write:users")),
+	} {
+		if err := rule.Evaluate(testClaims); err != nil {
+			t.Fatalf("expected rule '%s' to allow but received '%v'", rule, err)
+		}
+	}
+
+	err := All(tenant, RequireScopes("write:users")).Evaluate(testClaims)
+	denial, ok := err.(*Denial)
+	if !ok {
+		t.Fatalf("expected a denial but received '%v'", err)
+	}
+	if denial.Reason != "missing scope write:users" || !denial.RequiredScopes().Has("write:users") {
+		t.Fatalf("expected denial reason and scopes different than received '%s', '%v'", denial.Reason, denial.Scopes)
+	}
+	if err := RequireScopes("read:users").Evaluate(tokens.Claims{"scope": "* read:*"}); err == nil {
+		t.Fatal("expected wildcard scopes carried by the token not to satisfy the rule")
+	}
+	if err := Not(tenant).Evaluate(testClaims); err == nil || !strings.Contains(err.Error(), "claim tenant in") {
+		t.Fatalf("expected a not denial describing the rule but received '%v'", err)
+	}
+	if _, err := ClaimMatches("email", "("); err == nil {
+		t.Fatal("expected invalid regular expression error")
+	}
+}
+
+const testYAMLConfig = `
+routes:
+  - method: GET
+    path: /users/**
+    rule:
+      any:
+        - scopes: [read:users]
+        - any_role: {path: realm_access.roles, roles: [admin]}
+  - method: DELETE
+    path: /users/*
+    rule:
+      all:
+        - scopes: [write:users]
+        - not:
+            claim: {path: tenant, in: [blocked]}
+  - path: /health
+    rule:
+      allow: true
+`
+
+func TestLoadYAML(t *testing.T) {
+	policy, err := LoadYAML([]byte(testYAMLConfig))
+	if err != nil {
+		t.Fatalf("expected the policy to load but received '%v'", err)
+	}
+	if err := policy.Evaluate(http.MethodGet, "/users/1/groups", testClaims); err != nil {
+		t.Fatalf("expected GET to be allowed but received '%v'", err)
+	}
+	if err := policy.Evaluate(http.MethodDelete, "/users/1", testClaims); err == nil {
+		t.Fatal("expected DELETE to be denied")
+	}
+	if err := policy.Evaluate(http.MethodPost, "/health", tokens.Claims{}); err != nil {
+		t.Fatalf("expected health to be allowed but received '%v'", err)
+	}
+	if err := policy.Evaluate(http.MethodGet, "/unknown", testClaims); err == nil {
+		t.Fatal("expected unmatched route to be denied")
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	policy, err := LoadJSON([]byte(`{
+		"default": {"claim": {"path": "level", "equals": 3}},
+		"routes": [{"path": "/admin/**", "rule": {"deny": true}}]
+	}`))
+	if err != nil {
+		t.Fatalf("expected the policy to load but received '%v'", err)
+	}
+	if err := policy.Evaluate(http.MethodGet, "/other", testClaims); err != nil {
+		t.Fatalf("expected default rule to allow but received '%v'", err)
+	}
+	if err := policy.Evaluate(http.MethodGet, "/admin", testClaims); err == nil {
+		t.Fatal("expected /admin to be denied")
+	}
+	for _, invalid := range []string{
+		`{"routes": [{"path": "/", "rule": {}}]}`,
+		`{"routes": [{"path": "/", "rule": {"allow": true, "deny": true}}]}`,
+		`{"routes": [{"path": "/", "rule": {"claim": {"path": "a", "equals": 1, "matches": "b"}}}]}`,
+		`{"routes": [{"path": "[", "rule": {"allow": true}}]}`,
+		`{"routes": [{"path": "/", "rule": {"unknown": true}}]}`,
+	} {
+		if _, err := LoadJSON([]byte(invalid)); err == nil {
+			t.Fatalf("expected configuration '%s' not to load", invalid)
+		}
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	writeRule, _ := (&RuleConfig{Scopes: []string{"write:users"}}).Compile()
+	policy, _ := NewWithRoutes(Allow(), &Route{Method: http.MethodPost, Path: "/users", Rule: writeRule})
+	var authorizer bearer.Authorizer = policy
+	request := httptest.NewRequest(http.MethodPost, "/users", nil)
+	err := authorizer.Authorize(request, tokens.DefaultAccessToken(testClaims))
+	if denial, ok := err.(*Denial); !ok || !denial.Scopes.Has("write:users") {
+		t.Fatalf("expected a denial with the required scopes but received '%v'", err)
+	}
+}
+
+func TestAuthorizeNormalizedPath(t *testing.T) {
+	writeRule, _ := (&RuleConfig{Scopes: []string{"write:users"}}).Compile()
+	policy, _ := NewWithRoutes(Allow(),
+		&Route{Path: "/admin/**", Rule: writeRule},
+		&Route{Path: "/users/*", Rule: writeRule})
+	for _, target := range []string{
+		"//admin/x",
+		"/public/../admin/x",
+		"/public/%2e%2e/admin/x",
+		"/./admin/x",
+		"/users//x",
+		"/users/x/",
+	} {
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+		if err := policy.Authorize(request, tokens.DefaultAccessToken(testClaims)); err == nil {
+			t.Fatalf("expected the path '%s' to match the protected route", target)
+		}
+	}
+	if err := policy.Evaluate(http.MethodGet, "admin/x", testClaims); err == nil {
+		t.Fatal("expected a relative path to match the protected route")
+	}
+}
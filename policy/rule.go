@@ -0,0 +1,298 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/radekg/app-kit-tokens/tokens"
+)
+
+// Denial represents the reason a rule denied the access.
+type Denial struct {
+	Reason string
+	// Scopes: the scopes which would satisfy the rule, if known.
+	Scopes tokens.Scopes
+}
+
+func (d *Denial) Error() string {
+	return d.Reason
+}
+
+// RequiredScopes returns the scopes which would satisfy the rule.
+func (d *Denial) RequiredScopes() tokens.Scopes {
+	return d.Scopes
+}
+
+// Rule represents an authorization requirement over token claims.
+type Rule interface {
+	// Evaluate returns nil if the claims satisfy the rule, a *Denial otherwise.
+	Evaluate(claims tokens.Claims) error
+	// String returns the rule description.
+	String() string
+}
+
+type scopesRule struct {
+	scopes tokens.Scopes
+}
+
+// RequireScopes returns a rule requiring all of the scopes.
+// A required scope may be a pattern, see tokens.Scopes.Grants, the token scopes are matched literally.
+func RequireScopes(scopes ...string) Rule {
+	return &scopesRule{scopes: tokens.NewScopes(scopes...)}
+}
+
+func (r *scopesRule) Evaluate(claims tokens.Claims) error {
+	granted, _ := tokens.DefaultAccessToken(claims).Scopes()
+	for _, scope := range r.scopes {
+		if !granted.Grants(scope) {
+			return &Denial{Reason: fmt.Sprintf("missing scope %s", scope), Scopes: r.scopes}
+		}
+	}
+	return nil
+}
+func (r *scopesRule) String() string {
+	return fmt.Sprintf("scopes [%s]", r.scopes)
+}
+
+type anyRoleRule struct {
+	path  string
+	roles []string
+}
+
+// AnyRole returns a rule requiring any of the roles in the string array claim at the path.
+// See tokens.ParseClaimPath for the path syntax.
+func AnyRole(path string, roles ...string) (Rule, error) {
+	if _, err := tokens.ParseClaimPath(path); err != nil {
+		return nil, err
+	}
+	return &anyRoleRule{path: path, roles: roles}, nil
+}
+
+func (r *anyRoleRule) Evaluate(claims tokens.Claims) error {
+	granted, _ := claims.QueryStringSlice(r.path)
+	for _, role := range r.roles {
+		for _, item := range granted {
+			if item == role {
+				return nil
+			}
+		}
+	}
+	return &Denial{Reason: fmt.Sprintf("none of the roles [%s] in %s", strings.Join(r.roles, " "), r.path)}
+}
+func (r *anyRoleRule) String() string {
+	return fmt.Sprintf("any role [%s] in %s", strings.Join(r.roles, " "), r.path)
+}
+
+type claimInRule struct {
+	path   string
+	values []interface{}
+}
+
+// ClaimEquals returns a rule requiring the claim at the path to equal the value.
+// If the claim is an array, it must contain the value.
+func ClaimEquals(path string, value interface{}) (Rule, error) {
+	return ClaimIn(path, value)
+}
+
+// ClaimIn returns a rule requiring the claim at the path to equal any of the values.
+// If the claim is an array, it must contain any of the values.
+func ClaimIn(path string, values ...interface{}) (Rule, error) {
+	if _, err := tokens.ParseClaimPath(path); err != nil {
+		return nil, err
+	}
+	return &claimInRule{path: path, values: values}, nil
+}
+
+func (r *claimInRule) Evaluate(claims tokens.Claims) error {
+	value, err := claims.Query(r.path)
+	if err == nil {
+		for _, item := range claimItems(value) {
+			for _, expected := range r.values {
+				if equalValues(item, expected) {
+					return nil
+				}
+			}
+		}
+	}
+	return &Denial{Reason: fmt.Sprintf("claim %s not in %v", r.path, r.values)}
+}
+func (r *claimInRule) String() string {
+	return fmt.Sprintf("claim %s in %v", r.path, r.values)
+}
+
+type claimMatchesRule struct {
+	path    string
+	pattern *regexp.Regexp
+}
+
+// ClaimMatches returns a rule requiring the string claim at the path to match the regular expression.
+// If the claim is an array, any of its items must match.
+func ClaimMatches(path, pattern string) (Rule, error) {
+	if _, err := tokens.ParseClaimPath(path); err != nil {
+		return nil, err
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &claimMatchesRule{path: path, pattern: compiled}, nil
+}
+
+func (r *claimMatchesRule) Evaluate(claims tokens.Claims) error {
+	value, err := claims.Query(r.path)
+	if err == nil {
+		for _, item := range claimItems(value) {
+			if stringItem, ok := item.(string); ok && r.pattern.MatchString(stringItem) {
+				return nil
+			}
+		}
+	}
+	return &Denial{Reason: fmt.Sprintf("claim %s does not match %s", r.path, r.pattern)}
+}
+func (r *claimMatchesRule) String() string {
+	return fmt.Sprintf("claim %s matches %s", r.path, r.pattern)
+}
+
+type allRule struct {
+	rules []Rule
+}
+
+// All returns a rule requiring all of the rules.
+func All(rules ...Rule) Rule {
+	return &allRule{rules: rules}
+}
+
+func (r *allRule) Evaluate(claims tokens.Claims) error {
+	for _, rule := range r.rules {
+		if err := rule.Evaluate(claims); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (r *allRule) String() string {
+	return fmt.Sprintf("all (%s)", describe(r.rules))
+}
+
+type anyRule struct {
+	rules []Rule
+}
+
+// Any returns a rule requiring any of the rules.
+func Any(rules ...Rule) Rule {
+	return &anyRule{rules: rules}
+}
+
+func (r *anyRule) Evaluate(claims tokens.Claims) error {
+	reasons := []string{}
+	scopes := tokens.Scopes{}
+	for _, rule := range r.rules {
+		err := rule.Evaluate(claims)
+		if err == nil {
+			return nil
+		}
+		reasons = append(reasons, err.Error())
+		if denial, ok := err.(*Denial); ok {
+			scopes = scopes.Union(denial.Scopes)
+		}
+	}
+	return &Denial{Reason: fmt.Sprintf("none of: %s", strings.Join(reasons, "; ")), Scopes: scopes}
+}
+func (r *anyRule) String() string {
+	return fmt.Sprintf("any (%s)", describe(r.rules))
+}
+
+type notRule struct {
+	rule Rule
+}
+
+// Not returns a rule requiring the rule not to be satisfied.
+func Not(rule Rule) Rule {
+	return &notRule{rule: rule}
+}
+
+func (r *notRule) Evaluate(claims tokens.Claims) error {
+	if r.rule.Evaluate(claims) == nil {
+		return &Denial{Reason: fmt.Sprintf("forbidden: %s", r.rule)}
+	}
+	return nil
+}
+func (r *notRule) String() string {
+	return fmt.Sprintf("not (%s)", r.rule)
+}
+
+type constantRule struct {
+	allow bool
+}
+
+// Allow returns a rule satisfied by any claims.
+func Allow() Rule {
+	return &constantRule{allow: true}
+}
+
+// Deny returns a rule never satisfied.
+func Deny() Rule {
+	return &constantRule{allow: false}
+}
+
+func (r *constantRule) Evaluate(claims tokens.Claims) error {
+	if r.allow {
+		return nil
+	}
+	return &Denial{Reason: "denied"}
+}
+func (r *constantRule) String() string {
+	if r.allow {
+		return "allow"
+	}
+	return "deny"
+}
+
+func describe(rules []Rule) string {
+	descriptions := []string{}
+	for _, rule := range rules {
+		descriptions = append(descriptions, rule.String())
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+func claimItems(value interface{}) []interface{} {
+	if items, ok := value.([]interface{}); ok {
+		return items
+	}
+	return []interface{}{value}
+}
+
+// equalValues compares claim values, JSON numbers are compared as numbers
+// regardless of the integer or floating point representation in the configuration.
+func equalValues(claim, expected interface{}) bool {
+	claimNumber, claimIsNumber := toFloat64(claim)
+	expectedNumber, expectedIsNumber := toFloat64(expected)
+	if claimIsNumber && expectedIsNumber {
+		return claimNumber == expectedNumber
+	}
+	switch claim.(type) {
+	case string, bool:
+		return claim == expected
+	default:
+		return false
+	}
+}
+
+func toFloat64(value interface{}) (float64, bool) {
+	switch tvalue := value.(type) {
+	case float64:
+		return tvalue, true
+	case float32:
+		return float64(tvalue), true
+	case int:
+		return float64(tvalue), true
+	case int64:
+		return float64(tvalue), true
+	case uint64:
+		return float64(tvalue), true
+	default:
+		return 0, false
+	}
+}
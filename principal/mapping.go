@@ -0,0 +1,201 @@
+package principal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/radekg/app-kit-tokens/tokens"
+)
+
+var (
+	// ErrRequiredFieldMissing indicates a required principal field not resolved from the claims.
+	ErrRequiredFieldMissing = errRequiredFieldMissing()
+	// ErrUnknownTransform indicates a transform type which is not supported.
+	ErrUnknownTransform = errUnknownTransform()
+)
+
+func errRequiredFieldMissing() error { return errors.New("required field missing") }
+func errUnknownTransform() error     { return errors.New("unknown transform") }
+
+// Transform types:
+const (
+	// TransformLowercase lowercases the value.
+	TransformLowercase = "lowercase"
+	// TransformPrefix prepends the transform value to the value.
+	TransformPrefix = "prefix"
+	// TransformSplit splits the value by the transform value, by white space if empty.
+	TransformSplit = "split"
+	// TransformTrimPrefix removes the transform value from the beginning of the value.
+	TransformTrimPrefix = "trim_prefix"
+	// TransformUppercase uppercases the value.
+	TransformUppercase = "uppercase"
+)
+
+// Transform represents a value transformation.
+type Transform struct {
+	Type  string `json:"type"`
+	Value string `json:"value,omitempty"`
+}
+
+// FieldMapping represents the mapping of a single principal field.
+//
+// Single-valued fields take the value of the first path present in the claims,
+// multi-valued fields merge the values of all paths present in the claims.
+// See tokens.ParseClaimPath for the path syntax.
+type FieldMapping struct {
+	// Paths: claim paths the field is resolved from.
+	Paths []string `json:"paths"`
+	// Default: values used when none of the paths are present in the claims.
+	Default []string `json:"default,omitempty"`
+	// Required: fail the mapping when the field can't be resolved.
+	Required bool `json:"required,omitempty"`
+	// Transforms: transformations applied to every value, in order.
+	Transforms []Transform `json:"transforms,omitempty"`
+}
+
+// Mapping represents the declarative mapping of claims to the principal.
+// Fields without a mapping are left empty. AuthTime ignores defaults and transforms.
+type Mapping struct {
+	ACR         *FieldMapping `json:"acr,omitempty"`
+	AMR         *FieldMapping `json:"amr,omitempty"`
+	AuthTime    *FieldMapping `json:"auth_time,omitempty"`
+	ClientID    *FieldMapping `json:"client_id,omitempty"`
+	DisplayName *FieldMapping `json:"display_name,omitempty"`
+	Email       *FieldMapping `json:"email,omitempty"`
+	Groups      *FieldMapping `json:"groups,omitempty"`
+	Issuer      *FieldMapping `json:"issuer,omitempty"`
+	Roles       *FieldMapping `json:"roles,omitempty"`
+	Scopes      *FieldMapping `json:"scopes,omitempty"`
+	Subject     *FieldMapping `json:"subject,omitempty"`
+	Tenant      *FieldMapping `json:"tenant,omitempty"`
+}
+
+// DefaultMapping returns a mapping of the standard OpenID Connect claims
+// and of the commonly used roles, groups and tenant claims.
+func DefaultMapping() *Mapping {
+	return &Mapping{
+		ACR:         &FieldMapping{Paths: []string{"acr"}},
+		AMR:         &FieldMapping{Paths: []string{"amr"}},
+		AuthTime:    &FieldMapping{Paths: []string{"auth_time"}},
+		ClientID:    &FieldMapping{Paths: []string{"client_id", "azp", "cid"}},
+		DisplayName: &FieldMapping{Paths: []string{"name", "preferred_username"}},
+		Email:       &FieldMapping{Paths: []string{"email"}},
+		Groups:      &FieldMapping{Paths: []string{"groups"}},
+		Issuer:      &FieldMapping{Paths: []string{"iss"}},
+		Roles:       &FieldMapping{Paths: []string{"roles", "realm_access.roles"}},
+		Scopes: &FieldMapping{
+			Paths:      []string{"scp", "scope"},
+			Transforms: []Transform{{Type: TransformSplit}},
+		},
+		Subject: &FieldMapping{Paths: []string{"sub"}, Required: true},
+		Tenant:  &FieldMapping{Paths: []string{"tid", "tenant"}},
+	}
+}
+
+// LoadMapping loads the mapping from JSON.
+func LoadMapping(rawData []byte) (*Mapping, error) {
+	mapping := &Mapping{}
+	decoder := json.NewDecoder(bytes.NewReader(rawData))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(mapping); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+type compiledField struct {
+	name    string
+	mapping *FieldMapping
+}
+
+func compileField(name string, mapping *FieldMapping) (*compiledField, error) {
+	field := &compiledField{name: name, mapping: mapping}
+	if mapping == nil {
+		return field, nil
+	}
+	for _, path := range mapping.Paths {
+		if _, err := tokens.ParseClaimPath(path); err != nil {
+			return nil, fmt.Errorf("principal field %s path '%s': %w", name, path, err)
+		}
+	}
+	for _, transform := range mapping.Transforms {
+		switch transform.Type {
+		case TransformLowercase, TransformPrefix, TransformSplit, TransformTrimPrefix, TransformUppercase:
+		default:
+			return nil, fmt.Errorf("principal field %s transform '%s': %w", name, transform.Type, ErrUnknownTransform)
+		}
+	}
+	return field, nil
+}
+
+// resolve returns the field values, single-valued fields stop at the first present path.
+func (f *compiledField) resolve(claims tokens.Claims, single bool) ([]string, error) {
+	if f.mapping == nil {
+		return nil, nil
+	}
+	values := []string{}
+	found := false
+	for _, path := range f.mapping.Paths {
+		items, err := claims.QueryStringSlice(path)
+		if err == tokens.ErrClaimNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("principal field %s: %w", f.name, err)
+		}
+		values = append(values, items...)
+		found = true
+		if single {
+			break
+		}
+	}
+	if !found {
+		values = append(values, f.mapping.Default...)
+	}
+	values = applyTransforms(values, f.mapping.Transforms)
+	if len(values) == 0 && f.mapping.Required {
+		return nil, fmt.Errorf("principal field %s: %w", f.name, ErrRequiredFieldMissing)
+	}
+	return values, nil
+}
+
+func (f *compiledField) resolveString(claims tokens.Claims) (string, error) {
+	values, err := f.resolve(claims, true)
+	if err != nil || len(values) == 0 {
+		return "", err
+	}
+	return values[0], nil
+}
+
+func applyTransforms(values []string, transforms []Transform) []string {
+	for _, transform := range transforms {
+		transformed := []string{}
+		for _, value := range values {
+			switch transform.Type {
+			case TransformLowercase:
+				transformed = append(transformed, strings.ToLower(value))
+			case TransformPrefix:
+				transformed = append(transformed, transform.Value+value)
+			case TransformSplit:
+				if transform.Value == "" {
+					transformed = append(transformed, strings.Fields(value)...)
+				} else {
+					for _, item := range strings.Split(value, transform.Value) {
+						if item != "" {
+							transformed = append(transformed, item)
+						}
+					}
+				}
+			case TransformTrimPrefix:
+				transformed = append(transformed, strings.TrimPrefix(value, transform.Value))
+			case TransformUppercase:
+				transformed = append(transformed, strings.ToUpper(value))
+			}
+		}
+		values = transformed
+	}
+	return values
+}
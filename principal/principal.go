@@ -0,0 +1,227 @@
+package principal
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/radekg/app-kit-tokens/tokens"
+)
+
+// Principal represents the authenticated party independently of the identity provider claims layout.
+type Principal interface {
+	ACR() string
+	AMR() []string
+	AuthTime() time.Time
+	ClientID() string
+	DisplayName() string
+	Email() string
+	Groups() []string
+	Issuer() string
+	Roles() []string
+	Scopes() tokens.Scopes
+	Subject() string
+	Tenant() string
+	// Convenience checks:
+	HasGroup(group string) bool
+	HasRole(role string) bool
+	// Other convenience methods:
+	RawClaims() tokens.Claims
+}
+
+type defaultPrincipal struct {
+	acr         string
+	amr         []string
+	authTime    time.Time
+	claims      tokens.Claims
+	clientID    string
+	displayName string
+	email       string
+	groups      []string
+	issuer      string
+	roles       []string
+	scopes      tokens.Scopes
+	subject     string
+	tenant      string
+}
+
+func (p *defaultPrincipal) ACR() string {
+	return p.acr
+}
+func (p *defaultPrincipal) AMR() []string {
+	return p.amr
+}
+func (p *defaultPrincipal) AuthTime() time.Time {
+	return p.authTime
+}
+func (p *defaultPrincipal) ClientID() string {
+	return p.clientID
+}
+func (p *defaultPrincipal) DisplayName() string {
+	return p.displayName
+}
+func (p *defaultPrincipal) Email() string {
+	return p.email
+}
+func (p *defaultPrincipal) Groups() []string {
+	return p.groups
+}
+func (p *defaultPrincipal) Issuer() string {
+	return p.issuer
+}
+func (p *defaultPrincipal) Roles() []string {
+	return p.roles
+}
+func (p *defaultPrincipal) Scopes() tokens.Scopes {
+	return p.scopes
+}
+func (p *defaultPrincipal) Subject() string {
+	return p.subject
+}
+func (p *defaultPrincipal) Tenant() string {
+	return p.tenant
+}
+
+func (p *defaultPrincipal) HasGroup(group string) bool {
+	return containsString(p.groups, group)
+}
+func (p *defaultPrincipal) HasRole(role string) bool {
+	return containsString(p.roles, role)
+}
+
+func (p *defaultPrincipal) RawClaims() tokens.Claims {
+	return p.claims
+}
+
+// Mapper builds principals from token claims.
+type Mapper interface {
+	FromAccessToken(accessToken tokens.AccessToken) (Principal, error)
+	FromIDToken(idToken tokens.IDToken) (Principal, error)
+	Map(claims tokens.Claims) (Principal, error)
+}
+
+type defaultMapper struct {
+	acr         *compiledField
+	amr         *compiledField
+	authTime    *compiledField
+	clientID    *compiledField
+	displayName *compiledField
+	email       *compiledField
+	groups      *compiledField
+	issuer      *compiledField
+	roles       *compiledField
+	scopes      *compiledField
+	subject     *compiledField
+	tenant      *compiledField
+}
+
+// NewMapper returns a mapper for the mapping.
+// Claim paths and transforms are validated when the mapper is created.
+// DefaultMapping is used if the mapping is nil.
+func NewMapper(mapping *Mapping) (Mapper, error) {
+	if mapping == nil {
+		mapping = DefaultMapping()
+	}
+	mapper := &defaultMapper{}
+	for _, field := range []struct {
+		name   string
+		source *FieldMapping
+		target **compiledField
+	}{
+		{"acr", mapping.ACR, &mapper.acr},
+		{"amr", mapping.AMR, &mapper.amr},
+		{"auth_time", mapping.AuthTime, &mapper.authTime},
+		{"client_id", mapping.ClientID, &mapper.clientID},
+		{"display_name", mapping.DisplayName, &mapper.displayName},
+		{"email", mapping.Email, &mapper.email},
+		{"groups", mapping.Groups, &mapper.groups},
+		{"issuer", mapping.Issuer, &mapper.issuer},
+		{"roles", mapping.Roles, &mapper.roles},
+		{"scopes", mapping.Scopes, &mapper.scopes},
+		{"subject", mapping.Subject, &mapper.subject},
+		{"tenant", mapping.Tenant, &mapper.tenant},
+	} {
+		compiled, err := compileField(field.name, field.source)
+		if err != nil {
+			return nil, err
+		}
+		*field.target = compiled
+	}
+	return mapper, nil
+}
+
+func (m *defaultMapper) FromAccessToken(accessToken tokens.AccessToken) (Principal, error) {
+	return m.Map(accessToken.RawClaims())
+}
+
+func (m *defaultMapper) FromIDToken(idToken tokens.IDToken) (Principal, error) {
+	return m.Map(idToken.RawClaims())
+}
+
+func (m *defaultMapper) Map(claims tokens.Claims) (Principal, error) {
+	principal := &defaultPrincipal{claims: claims}
+	for _, field := range []struct {
+		source *compiledField
+		target *string
+	}{
+		{m.acr, &principal.acr},
+		{m.clientID, &principal.clientID},
+		{m.displayName, &principal.displayName},
+		{m.email, &principal.email},
+		{m.issuer, &principal.issuer},
+		{m.subject, &principal.subject},
+		{m.tenant, &principal.tenant},
+	} {
+		value, err := field.source.resolveString(claims)
+		if err != nil {
+			return nil, err
+		}
+		*field.target = value
+	}
+	for _, field := range []struct {
+		source *compiledField
+		target *[]string
+	}{
+		{m.amr, &principal.amr},
+		{m.groups, &principal.groups},
+		{m.roles, &principal.roles},
+	} {
+		values, err := field.source.resolve(claims, false)
+		if err != nil {
+			return nil, err
+		}
+		*field.target = values
+	}
+
+	scopes, err := m.scopes.resolve(claims, false)
+	if err != nil {
+		return nil, err
+	}
+	principal.scopes = tokens.NewScopes(scopes...)
+
+	if m.authTime.mapping != nil {
+		for _, path := range m.authTime.mapping.Paths {
+			authTime, err := claims.QueryTime(path)
+			if err == tokens.ErrClaimNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("principal field auth_time: %w", err)
+			}
+			principal.authTime = authTime
+			break
+		}
+		if principal.authTime.IsZero() && m.authTime.mapping.Required {
+			return nil, fmt.Errorf("principal field auth_time: %w", ErrRequiredFieldMissing)
+		}
+	}
+	return principal, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
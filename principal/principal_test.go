@@ -0,0 +1,107 @@
+package principal
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/radekg/app-kit-tokens/tokens"
+)
+
+func TestDefaultMapping(t *testing.T) {
+	mapper, err := NewMapper(nil)
+	if err != nil {
+		t.Fatalf("expected default mapper but received '%v'", err)
+	}
+	principal, err := mapper.FromAccessToken(tokens.DefaultAccessToken(tokens.Claims{
+		"sub":          "subject",
+		"iss":          "http://127.0.0.1:8081/auth/realms/multi-customer",
+		"azp":          "customers",
+		"scope":        "profile email",
+		"auth_time":    float64(1618150928),
+		"amr":          []interface{}{"pwd", "otp"},
+		"realm_access": map[string]interface{}{"roles": []interface{}{"offline_access"}},
+		"roles":        []interface{}{"admin"},
+		"email":        "member@service-team",
+	}))
+	if err != nil {
+		t.Fatalf("expected claims to map but received '%v'", err)
+	}
+	if principal.Subject() != "subject" || principal.ClientID() != "customers" || principal.Email() != "member@service-team" {
+		t.Fatalf("expected mapped principal different than received '%v'", principal)
+	}
+	if !principal.HasRole("admin") || !principal.HasRole("offline_access") {
+		t.Fatalf("expected roles merged from all paths but received '%v'", principal.Roles())
+	}
+	if !principal.Scopes().HasAll("profile", "email") {
+		t.Fatalf("expected split scopes but received '%v'", principal.Scopes())
+	}
+	if principal.AuthTime().Unix() != 1618150928 {
+		t.Fatalf("expected auth time different than received '%v'", principal.AuthTime())
+	}
+	if len(principal.AMR()) != 2 {
+		t.Fatalf("expected amr different than received '%v'", principal.AMR())
+	}
+	if _, err := mapper.Map(tokens.Claims{}); !errors.Is(err, ErrRequiredFieldMissing) {
+		t.Fatalf("expected required field missing error but received '%v'", err)
+	}
+}
+
+func TestLoadMapping(t *testing.T) {
+	mapping, err := LoadMapping([]byte(`{
+		"subject": {"paths": ["sub"], "required": true},
+		"tenant": {"paths": ["ext.tenant"], "default": ["default-tenant"]},
+		"roles": {
+			"paths": ["resource_access.customers.roles", "['https://example.com/roles']"],
+			"transforms": [{"type": "lowercase"}, {"type": "prefix", "value": "app:"}]
+		},
+		"groups": {"paths": ["groups"], "transforms": [{"type": "trim_prefix", "value": "/"}]},
+		"scopes": {"paths": ["scp"]}
+	}`))
+	if err != nil {
+		t.Fatalf("expected mapping to load but received '%v'", err)
+	}
+	mapper, err := NewMapper(mapping)
+	if err != nil {
+		t.Fatalf("expected mapper but received '%v'", err)
+	}
+	principal, err := mapper.Map(tokens.Claims{
+		"sub":                       "subject",
+		"resource_access":           map[string]interface{}{"customers": map[string]interface{}{"roles": []interface{}{"Viewer"}}},
+		"https://example.com/roles": "Editor",
+		"groups":                    []interface{}{"/admins"},
+		"scp":                       []interface{}{"openid", "offline"},
+	})
+	if err != nil {
+		t.Fatalf("expected claims to map but received '%v'", err)
+	}
+	if !principal.HasRole("app:viewer") || !principal.HasRole("app:editor") {
+		t.Fatalf("expected transformed roles but received '%v'", principal.Roles())
+	}
+	if !principal.HasGroup("admins") {
+		t.Fatalf("expected trimmed groups but received '%v'", principal.Groups())
+	}
+	if principal.Tenant() != "default-tenant" {
+		t.Fatalf("expected default tenant but received '%s'", principal.Tenant())
+	}
+	if principal.Issuer() != "" {
+		t.Fatalf("expected unmapped issuer to be empty but received '%s'", principal.Issuer())
+	}
+	if !principal.Scopes().HasAll("openid", "offline") {
+		t.Fatalf("expected scopes from an array but received '%v'", principal.Scopes())
+	}
+	if _, err := mapper.Map(tokens.Claims{"sub": "subject", "groups": float64(1)}); err == nil {
+		t.Fatal("expected type mismatch error for numeric groups")
+	}
+}
+
+func TestNewMapperErrors(t *testing.T) {
+	if _, err := NewMapper(&Mapping{Roles: &FieldMapping{Paths: []string{"a..b"}}}); !errors.Is(err, tokens.ErrInvalidClaimPath) {
+		t.Fatalf("expected invalid claim path error but received '%v'", err)
+	}
+	if _, err := NewMapper(&Mapping{Roles: &FieldMapping{Transforms: []Transform{{Type: "reverse"}}}}); !errors.Is(err, ErrUnknownTransform) {
+		t.Fatalf("expected unknown transform error but received '%v'", err)
+	}
+	if _, err := LoadMapping([]byte(`{"unknown": {}}`)); err == nil {
+		t.Fatal("expected unknown field error")
+	}
+}
@@ -0,0 +1,45 @@
+package providers
+
+import (
+	"github.com/radekg/app-kit-tokens/tokens"
+	"github.com/radekg/app-kit-tokens/webfinger"
+)
+
+type auth0Profile struct {
+	baseURL   string
+	namespace string
+}
+
+// Auth0 returns an Auth0 tenant profile.
+// Auth0 requires custom claims to be namespaced, the namespace is prepended
+// to the roles and groups claim names, for example: https://example.com/.
+// Auth0 RBAC permissions are mapped as scopes.
+func Auth0(domain, namespace string) Profile {
+	return &auth0Profile{
+		baseURL:   webfinger.Auth0BaseURL(domain),
+		namespace: namespace,
+	}
+}
+
+func (p *auth0Profile) Name() string {
+	return "auth0"
+}
+func (p *auth0Profile) BaseURL() string {
+	return p.baseURL
+}
+func (p *auth0Profile) DiscoveryURL() string {
+	return discoveryURL(p.baseURL)
+}
+
+// Validate checks the issuer, Auth0 issuer has a trailing slash.
+func (p *auth0Profile) Validate(claims tokens.Claims) error {
+	return validateIssuer(claims, p.baseURL+"/")
+}
+func (p *auth0Profile) Map(claims tokens.Claims) *Access {
+	access := newAccess(claims, "azp", "client_id")
+	access.Tenant = stringClaim(claims, "org_id")
+	access.Roles = stringsClaim(claims, p.namespace+"roles")
+	access.Groups = stringsClaim(claims, p.namespace+"groups")
+	access.Scopes = tokens.NewScopes(append(stringsClaim(claims, "scope"), stringsClaim(claims, "permissions")...)...)
+	return access
+}
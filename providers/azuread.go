@@ -0,0 +1,91 @@
+package providers
+
+import (
+	"strings"
+
+	"github.com/radekg/app-kit-tokens/tokens"
+	"github.com/radekg/app-kit-tokens/webfinger"
+)
+
+const azureADTenantIDPlaceholder = "{tenantid}"
+
+type azureADProfile struct {
+	allowedTenantIDs []string
+	baseURL          string
+	tenant           string
+}
+
+// AzureAD returns an Azure AD / Entra ID profile.
+// The tenant is a tenant ID, a verified domain or one of common, organizations and consumers.
+// Azure AD signs the tokens of every tenant with the same keys, so the tid claim is always checked.
+// With a tenant ID, the tid claim must equal it. With a verified domain, the tid claim must be one
+// of the allowed tenant IDs and tokens are rejected if none are given. With common, organizations
+// and consumers, the tid claim must be one of the allowed tenant IDs if given; without them
+// tokens of ANY tenant are accepted.
+// Multi-tenant discovery documents advertise the https://login.microsoftonline.com/{tenantid}/v2.0
+// issuer, the placeholder is substituted with the tid claim of the token.
+func AzureAD(tenant string, allowedTenantIDs ...string) Profile {
+	return &azureADProfile{
+		allowedTenantIDs: allowedTenantIDs,
+		baseURL:          webfinger.AzureADBaseURL(tenant),
+		tenant:           tenant,
+	}
+}
+
+func (p *azureADProfile) Name() string {
+	return "azuread"
+}
+func (p *azureADProfile) BaseURL() string {
+	return p.baseURL
+}
+func (p *azureADProfile) DiscoveryURL() string {
+	return discoveryURL(p.baseURL)
+}
+
+// Validate checks the issuer for both, v2.0 and v1.0 tokens.
+func (p *azureADProfile) Validate(claims tokens.Claims) error {
+	tenantID := stringClaim(claims, "tid")
+	if tenantID == "" {
+		return ErrTenantNotAccepted
+	}
+	if !p.tenantAccepted(tenantID) {
+		return ErrTenantNotAccepted
+	}
+	return validateIssuer(claims,
+		strings.Replace(webfinger.AzureADBaseURL(azureADTenantIDPlaceholder), azureADTenantIDPlaceholder, tenantID, 1),
+		"https://sts.windows.net/"+tenantID+"/")
+}
+
+func (p *azureADProfile) tenantAccepted(tenantID string) bool {
+	switch {
+	case p.tenant == "common" || p.tenant == "organizations" || p.tenant == "consumers":
+		if len(p.allowedTenantIDs) == 0 {
+			return true
+		}
+	case !strings.Contains(p.tenant, "."):
+		// a tenant ID, verified domains always contain a dot:
+		if !strings.EqualFold(p.tenant, tenantID) {
+			return false
+		}
+		if len(p.allowedTenantIDs) == 0 {
+			return true
+		}
+	}
+	for _, allowedTenantID := range p.allowedTenantIDs {
+		if strings.EqualFold(allowedTenantID, tenantID) {
+			return true
+		}
+	}
+	return false
+}
+
+// Map maps Azure AD claims. Azure AD carries delegated scopes in a space-delimited scp claim
+// and application permissions in the roles claim.
+func (p *azureADProfile) Map(claims tokens.Claims) *Access {
+	access := newAccess(claims, "azp", "appid")
+	access.Tenant = stringClaim(claims, "tid")
+	access.Roles = stringsClaim(claims, "roles")
+	access.Groups = stringsClaim(claims, "groups")
+	access.Scopes = tokens.NewScopes(stringsClaim(claims, "scp")...)
+	return access
+}
@@ -0,0 +1,41 @@
+package providers
+
+import (
+	"github.com/radekg/app-kit-tokens/tokens"
+	"github.com/radekg/app-kit-tokens/webfinger"
+)
+
+type cognitoProfile struct {
+	baseURL string
+}
+
+// Cognito returns an AWS Cognito user pool profile.
+func Cognito(region, userPoolID string) Profile {
+	return &cognitoProfile{
+		baseURL: webfinger.CognitoBaseURL(region, userPoolID),
+	}
+}
+
+func (p *cognitoProfile) Name() string {
+	return "cognito"
+}
+func (p *cognitoProfile) BaseURL() string {
+	return p.baseURL
+}
+func (p *cognitoProfile) DiscoveryURL() string {
+	return discoveryURL(p.baseURL)
+}
+func (p *cognitoProfile) Validate(claims tokens.Claims) error {
+	return validateIssuer(claims, p.baseURL)
+}
+
+// Map maps Cognito claims. Cognito carries the user pool groups in cognito:groups
+// and the IAM roles in cognito:roles. The client ID is in client_id for access tokens
+// and in aud for ID tokens.
+func (p *cognitoProfile) Map(claims tokens.Claims) *Access {
+	access := newAccess(claims, "client_id", "aud")
+	access.Roles = stringsClaim(claims, "cognito:roles")
+	access.Groups = stringsClaim(claims, "cognito:groups")
+	access.Scopes = tokens.NewScopes(stringsClaim(claims, "scope")...)
+	return access
+}
@@ -0,0 +1,51 @@
+package providers
+
+import (
+	"github.com/radekg/app-kit-tokens/tokens"
+	"github.com/radekg/app-kit-tokens/webfinger"
+)
+
+type googleProfile struct {
+	hostedDomains []string
+}
+
+// Google returns a Google profile.
+// If hosted domains are given, the hd claim must be one of them.
+func Google(hostedDomains ...string) Profile {
+	return &googleProfile{hostedDomains: hostedDomains}
+}
+
+func (p *googleProfile) Name() string {
+	return "google"
+}
+func (p *googleProfile) BaseURL() string {
+	return webfinger.GoogleBaseURL()
+}
+func (p *googleProfile) DiscoveryURL() string {
+	return discoveryURL(p.BaseURL())
+}
+
+// Validate checks the issuer, Google issues tokens with and without the URL scheme.
+func (p *googleProfile) Validate(claims tokens.Claims) error {
+	if err := validateIssuer(claims, p.BaseURL(), "accounts.google.com"); err != nil {
+		return err
+	}
+	if len(p.hostedDomains) == 0 {
+		return nil
+	}
+	hostedDomain := stringClaim(claims, "hd")
+	for _, allowedDomain := range p.hostedDomains {
+		if allowedDomain == hostedDomain {
+			return nil
+		}
+	}
+	return ErrTenantNotAccepted
+}
+
+// Map maps Google claims. The hosted domain is used as the tenant.
+func (p *googleProfile) Map(claims tokens.Claims) *Access {
+	access := newAccess(claims, "azp")
+	access.Tenant = stringClaim(claims, "hd")
+	access.Scopes = tokens.NewScopes(stringsClaim(claims, "scope")...)
+	return access
+}
@@ -0,0 +1,53 @@
+package providers
+
+import (
+	"github.com/radekg/app-kit-tokens/tokens"
+	"github.com/radekg/app-kit-tokens/webfinger"
+)
+
+type keycloakProfile struct {
+	baseURL  string
+	clientID string
+	realm    string
+}
+
+// Keycloak returns a Keycloak realm profile.
+// Roles are the realm roles and the roles of the client with the given ID.
+// If the client ID is empty, the roles of the authorized party client are used.
+func Keycloak(baseURL, realm, clientID string) Profile {
+	return &keycloakProfile{
+		baseURL:  webfinger.KeycloakBaseURL(baseURL, realm),
+		clientID: clientID,
+		realm:    realm,
+	}
+}
+
+func (p *keycloakProfile) Name() string {
+	return "keycloak"
+}
+func (p *keycloakProfile) BaseURL() string {
+	return p.baseURL
+}
+func (p *keycloakProfile) DiscoveryURL() string {
+	return discoveryURL(p.baseURL)
+}
+func (p *keycloakProfile) Validate(claims tokens.Claims) error {
+	return validateIssuer(claims, p.baseURL)
+}
+func (p *keycloakProfile) Map(claims tokens.Claims) *Access {
+	access := newAccess(claims, "azp")
+	access.Tenant = p.realm
+	access.Groups = stringsClaim(claims, "groups")
+	access.Scopes = tokens.NewScopes(stringsClaim(claims, "scope")...)
+
+	keycloakToken := tokens.DefaultKeycloakAccessToken(tokens.DefaultAccessToken(claims))
+	realmRoles, _ := keycloakToken.RealmRoles()
+	access.Roles = append(access.Roles, realmRoles...)
+	clientID := p.clientID
+	if clientID == "" {
+		clientID = access.ClientID
+	}
+	clientRoles, _ := keycloakToken.ClientRoles(clientID)
+	access.Roles = append(access.Roles, clientRoles...)
+	return access
+}
@@ -0,0 +1,43 @@
+package providers
+
+import (
+	"github.com/radekg/app-kit-tokens/tokens"
+	"github.com/radekg/app-kit-tokens/webfinger"
+)
+
+type oktaProfile struct {
+	baseURL string
+}
+
+// Okta returns an Okta authorization server profile.
+// An empty authorization server ID selects the Okta org authorization server.
+func Okta(domain, authorizationServerID string) Profile {
+	return &oktaProfile{
+		baseURL: webfinger.OktaBaseURL(domain, authorizationServerID),
+	}
+}
+
+func (p *oktaProfile) Name() string {
+	return "okta"
+}
+func (p *oktaProfile) BaseURL() string {
+	return p.baseURL
+}
+func (p *oktaProfile) DiscoveryURL() string {
+	return discoveryURL(p.baseURL)
+}
+func (p *oktaProfile) Validate(claims tokens.Claims) error {
+	return validateIssuer(claims, p.baseURL)
+}
+
+// Map maps Okta claims. Okta access tokens carry the scopes as an scp array
+// and the client ID in cid.
+func (p *oktaProfile) Map(claims tokens.Claims) *Access {
+	access := newAccess(claims, "cid", "azp")
+	access.Groups = stringsClaim(claims, "groups")
+	access.Scopes = tokens.NewScopes(stringsClaim(claims, "scp")...)
+	if len(access.Scopes) == 0 {
+		access.Scopes = tokens.NewScopes(stringsClaim(claims, "scope")...)
+	}
+	return access
+}
@@ -0,0 +1,134 @@
+package providers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/radekg/app-kit-tokens/tokens"
+	"github.com/radekg/app-kit-tokens/webfinger"
+)
+
+var (
+	// ErrIssuerNotAccepted indicates a token issued by an issuer the profile does not accept.
+	ErrIssuerNotAccepted = errIssuerNotAccepted()
+	// ErrTenantNotAccepted indicates a token issued for a tenant the profile does not accept.
+	ErrTenantNotAccepted = errTenantNotAccepted()
+	// ErrNoProfileMatched indicates a token not accepted by any of the profiles.
+	ErrNoProfileMatched = errNoProfileMatched()
+)
+
+func errIssuerNotAccepted() error { return errors.New("issuer not accepted") }
+func errTenantNotAccepted() error { return errors.New("tenant not accepted") }
+func errNoProfileMatched() error  { return errors.New("no profile matched") }
+
+// Profile represents an identity provider profile.
+// A profile knows the provider discovery URL format, the issuer quirks and the claims layout.
+type Profile interface {
+	// Name returns the profile name.
+	Name() string
+	// BaseURL returns the webfinger base URL.
+	BaseURL() string
+	// DiscoveryURL returns the OpenID configuration discovery URL.
+	DiscoveryURL() string
+	// Validate checks if the token claims were issued by the provider.
+	Validate(claims tokens.Claims) error
+	// Map maps provider specific claims into the common representation.
+	Map(claims tokens.Claims) *Access
+}
+
+// Access is the provider independent representation of token claims.
+type Access struct {
+	ClientID string
+	Groups   []string
+	Issuer   string
+	Roles    []string
+	Scopes   tokens.Scopes
+	Subject  string
+	Tenant   string
+}
+
+// Discover resolves the OpenID configuration of the profile.
+func Discover(profile Profile, client *http.Client) (webfinger.OpenIDConfiguration, error) {
+	if client == nil {
+		client = &http.Client{}
+	}
+	return webfinger.ResolveOpenIDConfigurationWithHTTPClient(profile.BaseURL(), client)
+}
+
+// Profiles is a list of profiles.
+type Profiles []Profile
+
+// Match returns the first profile accepting the token claims.
+func (p Profiles) Match(claims tokens.Claims) (Profile, error) {
+	for _, profile := range p {
+		if profile.Validate(claims) == nil {
+			return profile, nil
+		}
+	}
+	return nil, ErrNoProfileMatched
+}
+
+func discoveryURL(baseURL string) string {
+	return baseURL + "/.well-known/openid-configuration"
+}
+
+func validateIssuer(claims tokens.Claims, accepted ...string) error {
+	iss, ok := claims.GetClaim("iss")
+	if !ok {
+		return ErrIssuerNotAccepted
+	}
+	for _, issuer := range accepted {
+		if iss == issuer {
+			return nil
+		}
+	}
+	return ErrIssuerNotAccepted
+}
+
+func newAccess(claims tokens.Claims, clientIDClaims ...string) *Access {
+	access := &Access{
+		Issuer:  stringClaim(claims, "iss"),
+		Subject: stringClaim(claims, "sub"),
+	}
+	for _, claim := range clientIDClaims {
+		if clientID := stringClaim(claims, claim); clientID != "" {
+			access.ClientID = clientID
+			break
+		}
+	}
+	return access
+}
+
+func stringClaim(claims tokens.Claims, claim string) string {
+	if value, ok := claims.GetClaim(claim); ok {
+		if stringValue, ok := value.(string); ok {
+			return stringValue
+		}
+	}
+	return ""
+}
+
+// stringsClaim reads a claim given either as an array or as a space-delimited string.
+func stringsClaim(claims tokens.Claims, claim string) []string {
+	value, ok := claims.GetClaim(claim)
+	if !ok {
+		return nil
+	}
+	switch tvalue := value.(type) {
+	case string:
+		return strings.Fields(tvalue)
+	case []string:
+		return tvalue
+	case []interface{}:
+		result := []string{}
+		for _, item := range tvalue {
+			if stringItem, ok := item.(string); ok {
+				result = append(result, stringItem)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,133 @@
+package providers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/radekg/app-kit-tokens/tokens"
+)
+
+func TestKeycloakProfile(t *testing.T) {
+	profile := Keycloak("http://127.0.0.1:8081", "multi-customer", "account")
+	if profile.DiscoveryURL() != "http://127.0.0.1:8081/auth/realms/multi-customer/.well-known/openid-configuration" {
+		t.Fatalf("expected Keycloak discovery URL different than received '%s'", profile.DiscoveryURL())
+	}
+	claims := tokens.Claims{
+		"iss":             "http://127.0.0.1:8081/auth/realms/multi-customer",
+		"sub":             "subject",
+		"azp":             "customers",
+		"scope":           "profile email",
+		"realm_access":    map[string]interface{}{"roles": []interface{}{"offline_access"}},
+		"resource_access": map[string]interface{}{"account": map[string]interface{}{"roles": []interface{}{"view-profile"}}},
+	}
+	if err := profile.Validate(claims); err != nil {
+		t.Fatalf("expected claims to validate but received '%v'", err)
+	}
+	access := profile.Map(claims)
+	if len(access.Roles) != 2 || access.Roles[0] != "offline_access" || access.Roles[1] != "view-profile" {
+		t.Fatalf("expected roles '[offline_access view-profile]' but received '%v'", access.Roles)
+	}
+	if len(access.Scopes) != 2 {
+		t.Fatalf("expected 2 scopes but received '%v'", access.Scopes)
+	}
+	if access.ClientID != "customers" || access.Tenant != "multi-customer" {
+		t.Fatalf("expected client ID and tenant different than received '%s', '%s'", access.ClientID, access.Tenant)
+	}
+}
+
+func TestAzureADProfile(t *testing.T) {
+	claims := tokens.Claims{
+		"iss":   "https://login.microsoftonline.com/tenant-1/v2.0",
+		"tid":   "tenant-1",
+		"scp":   "User.Read Files.Read",
+		"roles": []interface{}{"Admin"},
+	}
+	if err := AzureAD("common").Validate(claims); err != nil {
+		t.Fatalf("expected claims to validate but received '%v'", err)
+	}
+	if err := AzureAD("common", "tenant-2").Validate(claims); err != ErrTenantNotAccepted {
+		t.Fatalf("expected tenant not accepted error but received '%v'", err)
+	}
+	if err := AzureAD("tenant-1").Validate(claims); err != nil {
+		t.Fatalf("expected claims of the configured tenant to validate but received '%v'", err)
+	}
+	if err := AzureAD("example.com", "tenant-1").Validate(claims); err != nil {
+		t.Fatalf("expected claims of the allowed tenant to validate but received '%v'", err)
+	}
+	if err := AzureAD("example.com").Validate(claims); err != ErrTenantNotAccepted {
+		t.Fatalf("expected tenant not accepted error without allowed tenants but received '%v'", err)
+	}
+	foreign := tokens.Claims{"iss": "https://login.microsoftonline.com/attacker/v2.0", "tid": "attacker"}
+	if err := AzureAD("tenant-1").Validate(foreign); err != ErrTenantNotAccepted {
+		t.Fatalf("expected the foreign tenant to be rejected but received '%v'", err)
+	}
+	claims["iss"] = "https://login.microsoftonline.com/tenant-2/v2.0"
+	if err := AzureAD("common").Validate(claims); err != ErrIssuerNotAccepted {
+		t.Fatalf("expected issuer not accepted error but received '%v'", err)
+	}
+	access := AzureAD("common").Map(claims)
+	if len(access.Scopes) != 2 || len(access.Roles) != 1 || access.Tenant != "tenant-1" {
+		t.Fatalf("expected mapped Azure AD access different than received '%v'", access)
+	}
+}
+
+func TestAuth0Profile(t *testing.T) {
+	profile := Auth0("example.eu.auth0.com", "https://example.com/")
+	claims := tokens.Claims{
+		"iss":                       "https://example.eu.auth0.com/",
+		"scope":                     "openid",
+		"permissions":               []interface{}{"read:users"},
+		"https://example.com/roles": []interface{}{"admin"},
+	}
+	if err := profile.Validate(claims); err != nil {
+		t.Fatalf("expected claims to validate but received '%v'", err)
+	}
+	access := profile.Map(claims)
+	if len(access.Roles) != 1 || access.Roles[0] != "admin" {
+		t.Fatalf("expected roles '[admin]' but received '%v'", access.Roles)
+	}
+	if len(access.Scopes) != 2 {
+		t.Fatalf("expected scopes '[openid read:users]' but received '%v'", access.Scopes)
+	}
+}
+
+func TestProfilesMatch(t *testing.T) {
+	profiles := Profiles{
+		Google("example.com"),
+		Cognito("eu-west-1", "eu-west-1_pool"),
+		Okta("example.okta.com", "default"),
+	}
+	profile, err := profiles.Match(tokens.Claims{
+		"iss":            "https://cognito-idp.eu-west-1.amazonaws.com/eu-west-1_pool",
+		"cognito:groups": []interface{}{"admins"},
+	})
+	if err != nil {
+		t.Fatalf("expected a profile to match but received '%v'", err)
+	}
+	if profile.Name() != "cognito" {
+		t.Fatalf("expected cognito profile but received '%s'", profile.Name())
+	}
+	if _, err := profiles.Match(tokens.Claims{"iss": "accounts.google.com", "hd": "other.com"}); err != ErrNoProfileMatched {
+		t.Fatalf("expected no profile matched error but received '%v'", err)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/auth/realms/test/.well-known/openid-configuration" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"issuer":"issuer-value"}`)
+	}))
+	defer testServer.Close()
+	openIDConfig, err := Discover(Keycloak(testServer.URL, "test", ""), nil)
+	if err != nil {
+		t.Fatalf("expected discovery to succeed but received '%v'", err)
+	}
+	if openIDConfig.Issuer() != "issuer-value" {
+		t.Fatalf("expected issuer different than received '%s'", openIDConfig.Issuer())
+	}
+}
@@ -0,0 +1,91 @@
+package signer
+
+import (
+	"time"
+
+	"github.com/radekg/app-kit-tokens/tokens"
+)
+
+// Builder builds the claims of a token.
+type Builder struct {
+	claims  tokens.Claims
+	options Options
+}
+
+// NewAccessToken returns a builder of an access token for the subject.
+// The typ header is at+jwt, as defined by RFC 9068, and the typ claim is Bearer.
+func NewAccessToken(subject string) *Builder {
+	return &Builder{
+		claims:  tokens.Claims{"sub": subject, "typ": "Bearer"},
+		options: Options{Type: "at+jwt"},
+	}
+}
+
+// NewIDToken returns a builder of an ID token for the subject and the client.
+// The typ claim is ID.
+func NewIDToken(subject, clientID string) *Builder {
+	return &Builder{
+		claims:  tokens.Claims{"sub": subject, "aud": clientID, "azp": clientID, "typ": "ID"},
+		options: Options{Type: "JWT"},
+	}
+}
+
+// NewRefreshToken returns a builder of a refresh token for the subject.
+// The typ claim is Refresh.
+func NewRefreshToken(subject string) *Builder {
+	return &Builder{
+		claims:  tokens.Claims{"sub": subject, "typ": "Refresh"},
+		options: Options{Type: "JWT"},
+	}
+}
+
+// Audience sets the aud claim.
+func (b *Builder) Audience(audience ...string) *Builder {
+	if len(audience) == 1 {
+		return b.Claim("aud", audience[0])
+	}
+	return b.Claim("aud", audience)
+}
+
+// AuthTime sets the auth_time claim.
+func (b *Builder) AuthTime(authTime time.Time) *Builder {
+	return b.Claim("auth_time", authTime.Unix())
+}
+
+// Claim sets a claim.
+func (b *Builder) Claim(name string, value interface{}) *Builder {
+	b.claims[name] = value
+	return b
+}
+
+// ClientID sets the client_id and azp claims.
+func (b *Builder) ClientID(clientID string) *Builder {
+	return b.Claim("client_id", clientID).Claim("azp", clientID)
+}
+
+// ExpiresIn sets the lifetime of the token.
+func (b *Builder) ExpiresIn(expiresIn time.Duration) *Builder {
+	b.options.ExpiresIn = expiresIn
+	return b
+}
+
+// Nonce sets the nonce claim.
+func (b *Builder) Nonce(nonce string) *Builder {
+	return b.Claim("nonce", nonce)
+}
+
+// Scopes sets the space separated scope claim.
+func (b *Builder) Scopes(scopes ...string) *Builder {
+	return b.Claim("scope", tokens.NewScopes(scopes...).String())
+}
+
+// Claims returns the claims built so far.
+func (b *Builder) Claims() tokens.Claims {
+	return b.claims
+}
+
+// Sign signs the token with the signer.
+func (b *Builder) Sign(signer Signer) (string, error) {
+	options := b.options
+	return signer.Sign(b.claims, &options)
+}
@@ -0,0 +1,140 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"sync"
+
+	"gopkg.in/square/go-jose.v2"
+)
+
+var (
+	// ErrNoSigningKey indicates a key ring without the signing key.
+	ErrNoSigningKey = errNoSigningKey()
+)
+
+func errNoSigningKey() error { return errors.New("no signing key") }
+
+// KeyRing holds the private signing keys.
+type KeyRing interface {
+	// SigningKey returns the private key used for signing new tokens.
+	SigningKey() (jose.JSONWebKey, error)
+	// VerificationKeys returns the public keys of the ring, suitable for publishing as JWKS.
+	VerificationKeys() jose.JSONWebKeySet
+}
+
+type defaultKeyRing struct {
+	sync.RWMutex
+	keys       []jose.JSONWebKey
+	signingKID string
+}
+
+// NewKeyRing returns a key ring signing with the key of the signingKID key ID.
+// Every key must be a private key with a key ID, the algorithm is inferred from the key
+// type when not set: RS256 for RSA, ES256, ES384 or ES512 for ECDSA, EdDSA for Ed25519
+// and HS256 for symmetric keys. Symmetric keys are not published as verification keys.
+func NewKeyRing(signingKID string, keys ...jose.JSONWebKey) (KeyRing, error) {
+	ring := &defaultKeyRing{signingKID: signingKID}
+	found := false
+	for _, key := range keys {
+		prepared, err := PrepareKey(key)
+		if err != nil {
+			return nil, err
+		}
+		found = found || prepared.KeyID == signingKID
+		ring.keys = append(ring.keys, prepared)
+	}
+	if !found {
+		return nil, ErrNoSigningKey
+	}
+	return ring, nil
+}
+
+func (r *defaultKeyRing) SigningKey() (jose.JSONWebKey, error) {
+	r.RLock()
+	defer r.RUnlock()
+	for _, key := range r.keys {
+		if key.KeyID == r.signingKID {
+			return key, nil
+		}
+	}
+	return jose.JSONWebKey{}, ErrNoSigningKey
+}
+
+func (r *defaultKeyRing) VerificationKeys() jose.JSONWebKeySet {
+	r.RLock()
+	defer r.RUnlock()
+	set := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
+	for _, key := range r.keys {
+		if _, ok := key.Key.([]byte); ok {
+			continue
+		}
+		set.Keys = append(set.Keys, key.Public())
+	}
+	return set
+}
+
+// PrepareKey validates a private signing key and sets its algorithm and use when not set.
+func PrepareKey(key jose.JSONWebKey) (jose.JSONWebKey, error) {
+	if key.KeyID == "" {
+		return key, errors.New("signing key without key ID")
+	}
+	if secret, ok := key.Key.([]byte); ok {
+		return prepareSymmetricKey(key, secret)
+	}
+	if !key.Valid() || key.IsPublic() {
+		return key, fmt.Errorf("invalid private key '%s'", key.KeyID)
+	}
+	if key.Algorithm == "" {
+		algorithm, err := inferAlgorithm(key.Key)
+		if err != nil {
+			return key, err
+		}
+		key.Algorithm = string(algorithm)
+	}
+	if key.Use == "" {
+		key.Use = "sig"
+	}
+	return key, nil
+}
+
+// prepareSymmetricKey checks the secret is at least as long as the HMAC output, RFC 7518 section 3.2.
+func prepareSymmetricKey(key jose.JSONWebKey, secret []byte) (jose.JSONWebKey, error) {
+	if key.Algorithm == "" {
+		key.Algorithm = string(jose.HS256)
+	}
+	minimum := map[string]int{string(jose.HS256): 32, string(jose.HS384): 48, string(jose.HS512): 64}[key.Algorithm]
+	if minimum == 0 {
+		return key, fmt.Errorf("algorithm '%s' of symmetric key '%s' is not an HMAC algorithm", key.Algorithm, key.KeyID)
+	}
+	if len(secret) < minimum {
+		return key, fmt.Errorf("symmetric key '%s' must be at least %d bytes long", key.KeyID, minimum)
+	}
+	if key.Use == "" {
+		key.Use = "sig"
+	}
+	return key, nil
+}
+
+func inferAlgorithm(key interface{}) (jose.SignatureAlgorithm, error) {
+	switch typed := key.(type) {
+	case *rsa.PrivateKey:
+		return jose.RS256, nil
+	case *ecdsa.PrivateKey:
+		switch typed.Curve {
+		case elliptic.P256():
+			return jose.ES256, nil
+		case elliptic.P384():
+			return jose.ES384, nil
+		case elliptic.P521():
+			return jose.ES512, nil
+		}
+	case ed25519.PrivateKey:
+		return jose.EdDSA, nil
+	}
+	return "", fmt.Errorf("cannot infer the algorithm of a key of type %T", key)
+}
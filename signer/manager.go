@@ -0,0 +1,251 @@
+package signer
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"sync"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+)
+
+// KeyState is the lifecycle state of a managed key.
+type KeyState string
+
+const (
+	// KeyStatePublished: the key is published for verification but not used for signing yet.
+	KeyStatePublished KeyState = "published"
+	// KeyStateActive: the key signs new tokens.
+	KeyStateActive KeyState = "active"
+	// KeyStateRetired: the key no longer signs but stays published until issued tokens expire.
+	KeyStateRetired KeyState = "retired"
+)
+
+// ManagedKey is a key managed by the key manager.
+type ManagedKey struct {
+	ActivatedAt time.Time       `json:"activated_at"`
+	Key         jose.JSONWebKey `json:"key"`
+	PublishedAt time.Time       `json:"published_at"`
+	RetiredAt   time.Time       `json:"retired_at"`
+	State       KeyState        `json:"state"`
+}
+
+// ManagerConfig represents the key manager configuration.
+type ManagerConfig struct {
+	// Algorithm: the algorithm of generated keys, one of RS256, RS384, RS512, PS256, PS384,
+	// PS512, ES256, ES384, ES512 or EdDSA, defaults to RS256.
+	Algorithm jose.SignatureAlgorithm
+	// Now: returns the current time, defaults to time.Now.
+	Now func() time.Time
+	// PublishBefore: how long a new key is published before it signs tokens. Must be longer
+	// than verifiers cache the JWKS so they know the key before the first token signed with it.
+	PublishBefore time.Duration
+	// RetainAfter: how long a retired key stays published. Must be longer than the lifetime
+	// of the tokens it signed.
+	RetainAfter time.Duration
+	// RotateEvery: how long a key signs tokens before it is replaced.
+	RotateEvery time.Duration
+}
+
+// Manager is a key ring rotating its signing key.
+//
+// A new key is published PublishBefore the active key is due for rotation, it is activated
+// once the active key is due and the new key has been published for at least PublishBefore.
+// The previous key is retired and removed RetainAfter later.
+// The verification keys include published, active and retired keys, serve them with
+// jwks.Handler(manager.VerificationKeys, maxAge) where maxAge is shorter than PublishBefore.
+type Manager interface {
+	KeyRing
+	// Keys returns the managed keys.
+	Keys() []ManagedKey
+	// Rotate advances the lifecycle of the keys, it has to be called periodically.
+	Rotate() error
+	// Run calls Rotate every interval until the context is done.
+	Run(ctx context.Context, interval time.Duration, onError func(error))
+}
+
+type defaultManager struct {
+	sync.RWMutex
+	config  *ManagerConfig
+	keys    []ManagedKey
+	storage Storage
+}
+
+// NewManager returns a key manager persisting the keys in the storage.
+// Existing keys are loaded and rotated, a key is generated and activated right away
+// if there is no key to activate.
+func NewManager(storage Storage, config *ManagerConfig) (Manager, error) {
+	if config.RotateEvery <= config.PublishBefore {
+		return nil, fmt.Errorf("rotation interval must be longer than the publication period")
+	}
+	keys, err := storage.Load()
+	if err != nil {
+		return nil, err
+	}
+	manager := &defaultManager{config: config, keys: keys, storage: storage}
+	if err := manager.Rotate(); err != nil {
+		return nil, err
+	}
+	return manager, nil
+}
+
+func (m *defaultManager) SigningKey() (jose.JSONWebKey, error) {
+	m.RLock()
+	defer m.RUnlock()
+	for _, managed := range m.keys {
+		if managed.State == KeyStateActive {
+			return managed.Key, nil
+		}
+	}
+	return jose.JSONWebKey{}, ErrNoSigningKey
+}
+
+func (m *defaultManager) VerificationKeys() jose.JSONWebKeySet {
+	m.RLock()
+	defer m.RUnlock()
+	set := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
+	for _, managed := range m.keys {
+		set.Keys = append(set.Keys, managed.Key.Public())
+	}
+	return set
+}
+
+func (m *defaultManager) Keys() []ManagedKey {
+	m.RLock()
+	defer m.RUnlock()
+	return append([]ManagedKey{}, m.keys...)
+}
+
+func (m *defaultManager) Rotate() error {
+	m.Lock()
+	defer m.Unlock()
+	now := time.Now()
+	if m.config.Now != nil {
+		now = m.config.Now()
+	}
+
+	keys := []ManagedKey{}
+	var active, published *ManagedKey
+	for _, managed := range m.keys {
+		if managed.State == KeyStateRetired && !now.Before(managed.RetiredAt.Add(m.config.RetainAfter)) {
+			continue
+		}
+		keys = append(keys, managed)
+	}
+	for index := range keys {
+		switch keys[index].State {
+		case KeyStateActive:
+			active = &keys[index]
+		case KeyStatePublished:
+			published = &keys[index]
+		}
+	}
+
+	switch {
+	case active == nil && published != nil:
+		// nothing signs, activating the published key early is the only option:
+		published.State, published.ActivatedAt = KeyStateActive, now
+	case active == nil:
+		key, err := m.generate(now)
+		if err != nil {
+			return err
+		}
+		key.State, key.ActivatedAt = KeyStateActive, now
+		keys = append(keys, key)
+	default:
+		rotateAt := active.ActivatedAt.Add(m.config.RotateEvery)
+		if published == nil && !now.Before(rotateAt.Add(-m.config.PublishBefore)) {
+			key, err := m.generate(now)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, key)
+			published = &keys[len(keys)-1]
+			active = m.find(keys, active.Key.KeyID)
+		}
+		if published != nil && !now.Before(rotateAt) && !now.Before(published.PublishedAt.Add(m.config.PublishBefore)) {
+			active.State, active.RetiredAt = KeyStateRetired, now
+			published.State, published.ActivatedAt = KeyStateActive, now
+		}
+	}
+
+	if err := m.storage.Save(keys); err != nil {
+		return err
+	}
+	m.keys = keys
+	return nil
+}
+
+func (m *defaultManager) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Rotate(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+func (m *defaultManager) find(keys []ManagedKey, kid string) *ManagedKey {
+	for index := range keys {
+		if keys[index].Key.KeyID == kid {
+			return &keys[index]
+		}
+	}
+	return nil
+}
+
+func (m *defaultManager) generate(now time.Time) (ManagedKey, error) {
+	algorithm := m.config.Algorithm
+	if algorithm == "" {
+		algorithm = jose.RS256
+	}
+	key, err := GenerateKey(algorithm)
+	if err != nil {
+		return ManagedKey{}, err
+	}
+	return ManagedKey{Key: key, PublishedAt: now, State: KeyStatePublished}, nil
+}
+
+// GenerateKey generates a private signing key for the algorithm, the key ID is the
+// RFC 7638 SHA-256 thumbprint of the key. RSA keys are 2048 bits long.
+func GenerateKey(algorithm jose.SignatureAlgorithm) (jose.JSONWebKey, error) {
+	var private interface{}
+	var err error
+	switch algorithm {
+	case jose.RS256, jose.RS384, jose.RS512, jose.PS256, jose.PS384, jose.PS512:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case jose.ES256:
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case jose.ES384:
+		private, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case jose.ES512:
+		private, err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	case jose.EdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return jose.JSONWebKey{}, fmt.Errorf("unsupported algorithm '%s'", algorithm)
+	}
+	if err != nil {
+		return jose.JSONWebKey{}, err
+	}
+	key := jose.JSONWebKey{Key: private, Algorithm: string(algorithm), Use: "sig"}
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return jose.JSONWebKey{}, err
+	}
+	key.KeyID = base64.RawURLEncoding.EncodeToString(thumbprint)
+	return key, nil
+}
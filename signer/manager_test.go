@@ -0,0 +1,118 @@
+package signer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/radekg/app-kit-tokens/jwks"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func TestManagerLifecycle(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	manager, err := NewManager(MemoryStorage(), &ManagerConfig{
+		Algorithm:     jose.ES256,
+		Now:           func() time.Time { return now },
+		PublishBefore: time.Hour,
+		RetainAfter:   2 * time.Hour,
+		RotateEvery:   24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("expected key manager but received '%v'", err)
+	}
+	first, err := manager.SigningKey()
+	if err != nil || first.Algorithm != string(jose.ES256) || first.KeyID == "" {
+		t.Fatalf("expected an active ES256 key but received '%v', '%v'", first, err)
+	}
+
+	// a verifier caching the JWKS while the key is published:
+	now = now.Add(23 * time.Hour)
+	manager.Rotate()
+	cached := jwks.FromKeySet(manager.VerificationKeys())
+	if keys := manager.Keys(); len(keys) != 2 || keys[1].State != KeyStatePublished {
+		t.Fatalf("expected the next key to be published but received '%v'", keys)
+	}
+	if key, _ := manager.SigningKey(); key.KeyID != first.KeyID {
+		t.Fatal("expected the first key to sign until rotation")
+	}
+
+	now = now.Add(time.Hour)
+	manager.Rotate()
+	second, _ := manager.SigningKey()
+	if second.KeyID == first.KeyID {
+		t.Fatal("expected the published key to be activated")
+	}
+	rawToken, _ := New(manager, nil).Sign(map[string]interface{}{"sub": "subject"}, nil)
+	if read := cached.ReadSigned(rawToken); read.Error() != nil {
+		t.Fatalf("expected the cached JWKS to know the new key but received '%v'", read.Error())
+	}
+	if keys := manager.Keys(); keys[0].State != KeyStateRetired {
+		t.Fatalf("expected the first key to be retired but received '%v'", keys[0].State)
+	}
+
+	now = now.Add(time.Hour)
+	manager.Rotate()
+	set := manager.VerificationKeys()
+	if keys := set.Key(first.KeyID); len(keys) != 1 {
+		t.Fatal("expected the retired key to stay published")
+	}
+	now = now.Add(time.Hour)
+	manager.Rotate()
+	set = manager.VerificationKeys()
+	if keys := set.Key(first.KeyID); len(keys) != 0 {
+		t.Fatal("expected the retired key to be removed")
+	}
+}
+
+func TestManagerDelaysActivationOfLatePublishedKey(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	manager, _ := NewManager(MemoryStorage(), &ManagerConfig{
+		Now:           func() time.Time { return now },
+		PublishBefore: time.Hour,
+		RetainAfter:   time.Hour,
+		RotateEvery:   24 * time.Hour,
+	})
+	first, _ := manager.SigningKey()
+	// Rotate was not called while the next key should have been published:
+	now = now.Add(25 * time.Hour)
+	manager.Rotate()
+	if key, _ := manager.SigningKey(); key.KeyID != first.KeyID {
+		t.Fatal("expected the active key to sign until the next key has been published long enough")
+	}
+	now = now.Add(time.Hour)
+	manager.Rotate()
+	if key, _ := manager.SigningKey(); key.KeyID == first.KeyID {
+		t.Fatal("expected the next key to be activated")
+	}
+}
+
+func TestFileStorage(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "signer")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "keys.json")
+	config := &ManagerConfig{Algorithm: jose.EdDSA, PublishBefore: time.Hour, RetainAfter: time.Hour, RotateEvery: 24 * time.Hour}
+
+	manager, err := NewManager(FileStorage(path), config)
+	if err != nil {
+		t.Fatalf("expected key manager but received '%v'", err)
+	}
+	key, _ := manager.SigningKey()
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("expected the keys file to be readable by the owner only but received '%v', '%v'", info, err)
+	}
+
+	reloaded, err := NewManager(FileStorage(path), config)
+	if err != nil {
+		t.Fatalf("expected key manager but received '%v'", err)
+	}
+	reloadedKey, _ := reloaded.SigningKey()
+	if reloadedKey.KeyID != key.KeyID || reloadedKey.IsPublic() {
+		t.Fatalf("expected the stored private key but received '%v'", reloadedKey)
+	}
+	if _, err := GenerateKey("none"); err == nil {
+		t.Fatal("expected an unsupported algorithm error")
+	}
+}
@@ -0,0 +1,115 @@
+package signer
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	"github.com/radekg/app-kit-tokens/tokens"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const defaultExpiresIn = time.Hour
+
+// Config represents the signer configuration.
+type Config struct {
+	// ExpiresIn: the lifetime of tokens without the exp claim, defaults to one hour.
+	ExpiresIn time.Duration
+	// Issuer: the iss claim of tokens without one, optional.
+	Issuer string
+	// Now: returns the current time, defaults to time.Now.
+	Now func() time.Time
+}
+
+// Options represents the options of a single token.
+type Options struct {
+	// ExpiresIn: the lifetime of the token, the configured lifetime is used if zero.
+	ExpiresIn time.Duration
+	// Type: the typ header, defaults to JWT.
+	Type string
+}
+
+// Signer signs tokens with the signing key of a key ring.
+type Signer interface {
+	// Sign returns the compact serialized JWS of the claims. The iss, iat, nbf, exp and jti
+	// claims are set unless already present. The options are optional.
+	Sign(claims tokens.Claims, options *Options) (string, error)
+}
+
+type defaultSigner struct {
+	config  *Config
+	keyRing KeyRing
+}
+
+// New returns a signer for the key ring.
+func New(keyRing KeyRing, config *Config) Signer {
+	if config == nil {
+		config = &Config{}
+	}
+	return &defaultSigner{config: config, keyRing: keyRing}
+}
+
+func (s *defaultSigner) Sign(claims tokens.Claims, options *Options) (string, error) {
+	if options == nil {
+		options = &Options{}
+	}
+	key, err := s.keyRing.SigningKey()
+	if err != nil {
+		return "", err
+	}
+	typ := options.Type
+	if typ == "" {
+		typ = "JWT"
+	}
+	joseSigner, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.SignatureAlgorithm(key.Algorithm), Key: key.Key},
+		(&jose.SignerOptions{}).WithHeader("kid", key.KeyID).WithType(jose.ContentType(typ)))
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	if s.config.Now != nil {
+		now = s.config.Now()
+	}
+	expiresIn := options.ExpiresIn
+	if expiresIn == 0 {
+		expiresIn = s.config.ExpiresIn
+	}
+	if expiresIn == 0 {
+		expiresIn = defaultExpiresIn
+	}
+	// go-jose requires a plain map:
+	payload := map[string]interface{}{}
+	for name, value := range claims {
+		payload[name] = value
+	}
+	setDefault(payload, "iat", now.Unix())
+	setDefault(payload, "nbf", now.Unix())
+	setDefault(payload, "exp", now.Add(expiresIn).Unix())
+	if s.config.Issuer != "" {
+		setDefault(payload, "iss", s.config.Issuer)
+	}
+	if _, ok := payload["jti"]; !ok {
+		jti, err := randomID()
+		if err != nil {
+			return "", err
+		}
+		payload["jti"] = jti
+	}
+	return jwt.Signed(joseSigner).Claims(payload).CompactSerialize()
+}
+
+func setDefault(payload map[string]interface{}, claim string, value interface{}) {
+	if _, ok := payload[claim]; !ok {
+		payload[claim] = value
+	}
+}
+
+func randomID() (string, error) {
+	value := make([]byte, 16)
+	if _, err := rand.Read(value); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(value), nil
+}
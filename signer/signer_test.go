@@ -0,0 +1,142 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/radekg/app-kit-tokens/jwks"
+	"github.com/radekg/app-kit-tokens/tokens"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func testKeyRing(t *testing.T) KeyRing {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	keyRing, err := NewKeyRing("ec", jose.JSONWebKey{Key: rsaKey, KeyID: "rsa"}, jose.JSONWebKey{Key: ecKey, KeyID: "ec"})
+	if err != nil {
+		t.Fatalf("expected key ring but received '%v'", err)
+	}
+	return keyRing
+}
+
+func TestKeyRing(t *testing.T) {
+	keyRing := testKeyRing(t)
+	key, err := keyRing.SigningKey()
+	if err != nil || key.KeyID != "ec" || key.Algorithm != string(jose.ES384) || key.Use != "sig" {
+		t.Fatalf("expected the ES384 signing key but received '%v', '%v'", key, err)
+	}
+	set := keyRing.VerificationKeys()
+	if len(set.Keys) != 2 || !set.Keys[0].IsPublic() || !set.Keys[1].IsPublic() {
+		t.Fatalf("expected two public keys but received '%v'", set.Keys)
+	}
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	if _, err := NewKeyRing("missing", jose.JSONWebKey{Key: rsaKey, KeyID: "rsa"}); err != ErrNoSigningKey {
+		t.Fatalf("expected ErrNoSigningKey but received '%v'", err)
+	}
+	if _, err := NewKeyRing("rsa", jose.JSONWebKey{Key: &rsaKey.PublicKey, KeyID: "rsa"}); err == nil {
+		t.Fatal("expected a public key to be rejected")
+	}
+	if _, err := NewKeyRing("", jose.JSONWebKey{Key: rsaKey}); err == nil {
+		t.Fatal("expected a key without key ID to be rejected")
+	}
+}
+
+func TestSymmetricKey(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	keyRing, err := NewKeyRing("hmac", jose.JSONWebKey{Key: secret, KeyID: "hmac"})
+	if err != nil {
+		t.Fatalf("expected key ring with the symmetric key but received '%v'", err)
+	}
+	if key, _ := keyRing.SigningKey(); key.Algorithm != string(jose.HS256) {
+		t.Fatalf("expected the inferred HS256 algorithm but received '%s'", key.Algorithm)
+	}
+	if set := keyRing.VerificationKeys(); len(set.Keys) != 0 {
+		t.Fatalf("expected the symmetric key not to be published but received '%v'", set.Keys)
+	}
+	rawToken, err := NewAccessToken("subject").Sign(New(keyRing, nil))
+	if err != nil {
+		t.Fatalf("expected HS256 token but received '%v'", err)
+	}
+	token, err := jwt.ParseSigned(rawToken)
+	if err != nil || token.Headers[0].Algorithm != string(jose.HS256) {
+		t.Fatalf("expected HS256 token but received '%v'", err)
+	}
+	claims := map[string]interface{}{}
+	if err := token.Claims(secret, &claims); err != nil || claims["sub"] != "subject" {
+		t.Fatalf("expected the token to verify with the secret but received '%v', '%v'", claims, err)
+	}
+	if _, err := NewKeyRing("short", jose.JSONWebKey{Key: []byte("short"), KeyID: "short"}); err == nil {
+		t.Fatal("expected a short secret to be rejected")
+	}
+}
+
+func TestSign(t *testing.T) {
+	keyRing := testKeyRing(t)
+	keySet := jwks.FromKeySet(keyRing.VerificationKeys())
+	now := time.Unix(1600000000, 0)
+	signer := New(keyRing, &Config{Issuer: "https://issuer.example.com", ExpiresIn: time.Minute, Now: func() time.Time { return now }})
+
+	rawToken, err := NewAccessToken("subject").
+		Audience("api").
+		ClientID("client").
+		Scopes("write", "read", "read").
+		Claim("tenant", "acme").
+		Sign(signer)
+	if err != nil {
+		t.Fatalf("expected signed access token but received '%v'", err)
+	}
+	read := keySet.ReadSigned(rawToken)
+	if read.Error() != nil {
+		t.Fatalf("expected access token to verify but received '%v'", read.Error())
+	}
+	if headers := read.Headers(); headers[0].KeyID != "ec" || headers[0].Algorithm != string(jose.ES384) || headers[0].ExtraHeaders["typ"] != "at+jwt" {
+		t.Fatalf("expected kid, alg and typ headers but received '%v'", headers[0])
+	}
+	accessToken := tokens.DefaultAccessToken(read.Claims())
+	if iss, _ := accessToken.Iss(); iss != "https://issuer.example.com" {
+		t.Fatalf("expected the configured issuer but received '%s'", iss)
+	}
+	if exp, _ := accessToken.Exp(); exp != now.Add(time.Minute).Unix() {
+		t.Fatalf("expected exp to be set from the configured lifetime but received '%d'", exp)
+	}
+	if scope, _ := accessToken.Scope(); scope != "read write" {
+		t.Fatalf("expected the scope claim but received '%s'", scope)
+	}
+	if jti, _ := accessToken.Jti(); jti == "" {
+		t.Fatal("expected the jti claim")
+	}
+	if clientID, _ := accessToken.ClientID(); clientID != "client" {
+		t.Fatalf("expected the client_id claim but received '%s'", clientID)
+	}
+
+	rawToken, _ = NewIDToken("subject", "client").Nonce("nonce").ExpiresIn(time.Hour).Sign(signer)
+	idClaims := keySet.ReadSigned(rawToken).Claims()
+	if nonce, _ := idClaims.GetClaimMustString("nonce"); nonce != "nonce" {
+		t.Fatalf("expected the nonce claim but received '%v'", idClaims)
+	}
+	if exp, _ := idClaims.GetInt64("exp"); exp != now.Add(time.Hour).Unix() {
+		t.Fatalf("expected exp to be set from the builder lifetime but received '%d'", exp)
+	}
+
+	rawToken, _ = NewRefreshToken("subject").Sign(signer)
+	refreshToken := tokens.DefaultRefreshToken(keySet.ReadSigned(rawToken).Claims())
+	if typ, _ := refreshToken.Typ(); typ != "Refresh" {
+		t.Fatalf("expected the Refresh typ claim but received '%s'", typ)
+	}
+
+	// claims set by the caller take precedence:
+	rawToken, _ = signer.Sign(tokens.Claims{"iss": "other", "exp": now.Add(time.Second).Unix(), "jti": "fixed"}, nil)
+	claims := keySet.ReadSigned(rawToken).Claims()
+	if iss, _ := claims.GetString("iss"); iss != "other" {
+		t.Fatalf("expected the given issuer but received '%s'", iss)
+	}
+	if jti, _ := claims.GetString("jti"); jti != "fixed" {
+		t.Fatalf("expected the given jti but received '%s'", jti)
+	}
+}
@@ -0,0 +1,89 @@
+package signer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Storage persists the managed keys.
+type Storage interface {
+	// Load returns the stored keys, no keys if nothing has been stored yet.
+	Load() ([]ManagedKey, error)
+	// Save replaces the stored keys.
+	Save(keys []ManagedKey) error
+}
+
+type memoryStorage struct {
+	sync.Mutex
+	keys []ManagedKey
+}
+
+// MemoryStorage returns a storage keeping the keys in memory.
+func MemoryStorage() Storage {
+	return &memoryStorage{}
+}
+
+func (s *memoryStorage) Load() ([]ManagedKey, error) {
+	s.Lock()
+	defer s.Unlock()
+	return append([]ManagedKey{}, s.keys...), nil
+}
+
+func (s *memoryStorage) Save(keys []ManagedKey) error {
+	s.Lock()
+	defer s.Unlock()
+	s.keys = append([]ManagedKey{}, keys...)
+	return nil
+}
+
+type fileStorage struct {
+	path string
+}
+
+// FileStorage returns a storage keeping the keys, including the private keys, in a JSON file
+// readable by the owner only. The file is replaced atomically on save.
+func FileStorage(path string) Storage {
+	return &fileStorage{path: path}
+}
+
+func (s *fileStorage) Load() ([]ManagedKey, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return []ManagedKey{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	keys := []ManagedKey{}
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (s *fileStorage) Save(keys []ManagedKey) error {
+	data, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	temp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if err := temp.Chmod(0600); err != nil {
+		temp.Close()
+		return err
+	}
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), s.path)
+}
@@ -0,0 +1,154 @@
+package tokenclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/radekg/app-kit-tokens/tokens"
+	"github.com/radekg/app-kit-tokens/webfinger"
+)
+
+var (
+	// ErrInvalidResponse indicates a token endpoint response without the required parameters.
+	ErrInvalidResponse = errInvalidResponse()
+	// ErrNoTokenEndpoint indicates a client without the token endpoint.
+	ErrNoTokenEndpoint = errNoTokenEndpoint()
+)
+
+func errInvalidResponse() error { return errors.New("invalid token endpoint response") }
+func errNoTokenEndpoint() error { return errors.New("token endpoint not configured") }
+
+// ResponseError represents an RFC 6749 error response.
+type ResponseError struct {
+	Code        string
+	Description string
+	StatusCode  int
+	URI         string
+}
+
+func (e *ResponseError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("%s: %s", e.Code, e.Description)
+	}
+	if e.Code != "" {
+		return e.Code
+	}
+	return fmt.Sprintf("unexpected status code %d", e.StatusCode)
+}
+
+// Config represents the client configuration.
+type Config struct {
+	// ClientID: the client identifier.
+	ClientID string
+	// ClientSecret: the client secret sent with client_secret_basic, optional.
+	// Public clients without the secret send the client_id form parameter.
+	ClientSecret string
+	// DeviceAuthorizationEndpoint: the RFC 8628 device authorization endpoint URL, optional.
+	DeviceAuthorizationEndpoint string
+	// HTTPClient: the HTTP client, defaults to http.DefaultClient.
+	// Use a client with the dpop transport or the mtls client certificate for sender-constrained tokens.
+	HTTPClient *http.Client
+	// MTLS: Discover selects the mtls_endpoint_aliases endpoints, where available.
+	MTLS bool
+	// TokenEndpoint: the token endpoint URL.
+	TokenEndpoint string
+}
+
+// Client calls the endpoints of the authorization server.
+type Client interface {
+	// DeviceAuthorization requests the device and user codes, RFC 8628.
+	DeviceAuthorization(ctx context.Context, scopes tokens.Scopes) (*DeviceAuthorization, error)
+	// DeviceFlow requests the device authorization, calls prompt to display the user code and
+	// waits for the user to complete the authorization. Cancel the context to stop waiting.
+	// The prompt is required.
+	DeviceFlow(ctx context.Context, scopes tokens.Scopes, prompt func(authorization *DeviceAuthorization) error) (tokens.JWT, error)
+	// DeviceToken polls the token endpoint until the user completes the device authorization,
+	// the authorization is denied, the device code expires or the context is cancelled.
+	// The polling interval doubles after every connection error.
+	DeviceToken(ctx context.Context, authorization *DeviceAuthorization) (tokens.JWT, error)
+	// Exchange exchanges the subject token for a new token, RFC 8693.
+	Exchange(ctx context.Context, request *ExchangeRequest) (ExchangedToken, error)
+}
+
+type defaultClient struct {
+	config *Config
+	wait   func(d time.Duration) <-chan time.Time
+}
+
+// New returns a client for the configuration.
+func New(config *Config) Client {
+	return &defaultClient{config: config, wait: time.After}
+}
+
+// Discover returns a client for the endpoints of the OpenID configuration.
+// Endpoints set in the configuration take precedence.
+func Discover(openIDConfig webfinger.OpenIDConfiguration, config *Config) Client {
+	discovered := *config
+	if discovered.TokenEndpoint == "" {
+		discovered.TokenEndpoint = openIDConfig.TokenEndpoint()
+		if config.MTLS {
+			discovered.TokenEndpoint = openIDConfig.MTLSTokenEndpoint()
+		}
+	}
+	if discovered.DeviceAuthorizationEndpoint == "" {
+		discovered.DeviceAuthorizationEndpoint = openIDConfig.DeviceAuthorizationEndpoint()
+		if aliases := openIDConfig.MTLSEndpointAliases(); config.MTLS && aliases != nil && aliases.DeviceAuthorizationEndpoint != "" {
+			discovered.DeviceAuthorizationEndpoint = aliases.DeviceAuthorizationEndpoint
+		}
+	}
+	return New(&discovered)
+}
+
+// post posts the form to the endpoint and returns the body of the successful response.
+// Error responses are returned as *ResponseError.
+func (c *defaultClient) post(ctx context.Context, endpoint string, form url.Values) ([]byte, error) {
+	if c.config.ClientSecret == "" && c.config.ClientID != "" {
+		form.Set("client_id", c.config.ClientID)
+	}
+	request, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if c.config.ClientSecret != "" {
+		// RFC 6749: the credentials are form-encoded before the basic authentication encoding
+		request.SetBasicAuth(url.QueryEscape(c.config.ClientID), url.QueryEscape(c.config.ClientSecret))
+	}
+	httpClient := c.config.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		responseError := &ResponseError{StatusCode: resp.StatusCode}
+		errorBody := struct {
+			Code        string `json:"error"`
+			Description string `json:"error_description"`
+			URI         string `json:"error_uri"`
+		}{}
+		if json.Unmarshal(body, &errorBody) == nil {
+			responseError.Code = errorBody.Code
+			responseError.Description = errorBody.Description
+			responseError.URI = errorBody.URI
+		}
+		return nil, responseError
+	}
+	return body, nil
+}
@@ -0,0 +1,167 @@
+package tokenclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/radekg/app-kit-tokens/tokens"
+)
+
+var (
+	// ErrDeviceCodeExpired indicates a device code which expired before the user completed the authorization.
+	ErrDeviceCodeExpired = errDeviceCodeExpired()
+	// ErrNoDeviceAuthorizationEndpoint indicates a client without the device authorization endpoint.
+	ErrNoDeviceAuthorizationEndpoint = errNoDeviceAuthorizationEndpoint()
+	// ErrPromptRequired indicates a device flow without the prompt displaying the user code.
+	ErrPromptRequired = errPromptRequired()
+)
+
+func errDeviceCodeExpired() error { return errors.New("device code expired") }
+func errNoDeviceAuthorizationEndpoint() error {
+	return errors.New("device authorization endpoint not configured")
+}
+func errPromptRequired() error { return errors.New("device flow prompt required") }
+
+// GrantTypeDeviceCode is the RFC 8628 grant type.
+const GrantTypeDeviceCode = "urn:ietf:params:oauth:grant-type:device_code"
+
+// RFC 8628 token endpoint error codes:
+const (
+	// ErrorCodeAuthorizationPending indicates the user has not completed the authorization yet.
+	ErrorCodeAuthorizationPending = "authorization_pending"
+	// ErrorCodeExpiredToken indicates a device code which expired, the authorization must be started again.
+	ErrorCodeExpiredToken = "expired_token"
+	// ErrorCodeSlowDown asks the client to increase the polling interval by five seconds.
+	ErrorCodeSlowDown = "slow_down"
+)
+
+const (
+	defaultDeviceInterval = 5 * time.Second
+	maxConnectionBackoff  = time.Minute
+	slowDownIncrement     = 5 * time.Second
+)
+
+// DeviceAuthorization represents the RFC 8628 device authorization response.
+type DeviceAuthorization struct {
+	DeviceCode string `json:"device_code"`
+	// ExpiresAt: the time the device code expires at.
+	ExpiresAt time.Time `json:"-"`
+	ExpiresIn int64     `json:"expires_in"`
+	// Interval: the minimum polling interval in seconds.
+	Interval        int64  `json:"interval"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	// VerificationURIComplete: the verification URI including the user code, optional.
+	// Display it as a QR code or open it in a browser where available.
+	VerificationURIComplete string `json:"verification_uri_complete"`
+}
+
+func (c *defaultClient) DeviceAuthorization(ctx context.Context, scopes tokens.Scopes) (*DeviceAuthorization, error) {
+	if c.config.DeviceAuthorizationEndpoint == "" {
+		return nil, ErrNoDeviceAuthorizationEndpoint
+	}
+	form := url.Values{}
+	if len(scopes) > 0 {
+		form.Set("scope", scopes.String())
+	}
+	requestedAt := time.Now()
+	body, err := c.post(ctx, c.config.DeviceAuthorizationEndpoint, form)
+	if err != nil {
+		return nil, err
+	}
+	authorization := &DeviceAuthorization{}
+	if err := json.Unmarshal(body, authorization); err != nil {
+		return nil, err
+	}
+	if authorization.DeviceCode == "" || authorization.UserCode == "" || authorization.VerificationURI == "" ||
+		authorization.ExpiresIn <= 0 {
+		return nil, ErrInvalidResponse
+	}
+	authorization.ExpiresAt = requestedAt.Add(time.Duration(authorization.ExpiresIn) * time.Second)
+	return authorization, nil
+}
+
+func (c *defaultClient) DeviceFlow(ctx context.Context, scopes tokens.Scopes, prompt func(authorization *DeviceAuthorization) error) (tokens.JWT, error) {
+	if prompt == nil {
+		return nil, ErrPromptRequired
+	}
+	authorization, err := c.DeviceAuthorization(ctx, scopes)
+	if err != nil {
+		return nil, err
+	}
+	if err := prompt(authorization); err != nil {
+		return nil, err
+	}
+	return c.DeviceToken(ctx, authorization)
+}
+
+func (c *defaultClient) DeviceToken(ctx context.Context, authorization *DeviceAuthorization) (tokens.JWT, error) {
+	if c.config.TokenEndpoint == "" {
+		return nil, ErrNoTokenEndpoint
+	}
+	interval := defaultDeviceInterval
+	if authorization.Interval > 0 {
+		interval = time.Duration(authorization.Interval) * time.Second
+	}
+	for {
+		wait := interval
+		if !authorization.ExpiresAt.IsZero() {
+			// never wait past the expiry of the device code:
+			if remaining := time.Until(authorization.ExpiresAt); remaining < wait {
+				wait = remaining
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-c.wait(wait):
+		}
+		if !authorization.ExpiresAt.IsZero() && time.Now().After(authorization.ExpiresAt) {
+			return nil, ErrDeviceCodeExpired
+		}
+		body, err := c.post(ctx, c.config.TokenEndpoint, url.Values{
+			"device_code": {authorization.DeviceCode},
+			"grant_type":  {GrantTypeDeviceCode},
+		})
+		if responseError, ok := err.(*ResponseError); ok {
+			switch responseError.Code {
+			case ErrorCodeAuthorizationPending:
+				continue
+			case ErrorCodeSlowDown:
+				interval = interval + slowDownIncrement
+				continue
+			case ErrorCodeExpiredToken:
+				return nil, ErrDeviceCodeExpired
+			}
+			return nil, err
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			// RFC 8628 section 3.5: back off exponentially on connection errors,
+			// up to a minute, until the device code expires:
+			if authorization.ExpiresAt.IsZero() {
+				return nil, err
+			}
+			if interval < maxConnectionBackoff {
+				interval = interval * 2
+				if interval > maxConnectionBackoff {
+					interval = maxConnectionBackoff
+				}
+			}
+			continue
+		}
+		jwt, err := tokens.DefaultJWT(body)
+		if err != nil {
+			return nil, err
+		}
+		if jwt.AccessToken() == "" {
+			return nil, ErrInvalidResponse
+		}
+		return jwt, nil
+	}
+}
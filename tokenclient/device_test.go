@@ -0,0 +1,179 @@
+package tokenclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/radekg/app-kit-tokens/oidctest"
+	"github.com/radekg/app-kit-tokens/tokens"
+	"github.com/radekg/app-kit-tokens/webfinger"
+)
+
+func TestDeviceFlow(t *testing.T) {
+	provider, _ := oidctest.New(&oidctest.Config{Users: map[string]oidctest.User{
+		"alice": {Claims: tokens.Claims{"sub": "user-1"}},
+	}})
+	defer provider.Close()
+	openIDConfig, err := webfinger.ResolveOpenIDConfiguration(provider.Issuer())
+	if err != nil {
+		t.Fatalf("expected the OpenID configuration but received '%v'", err)
+	}
+	client := Discover(openIDConfig, &Config{ClientID: oidctest.DefaultClientID, ClientSecret: oidctest.DefaultClientSecret}).(*defaultClient)
+
+	userCode, intervals := "", []time.Duration{}
+	client.wait = func(d time.Duration) <-chan time.Time {
+		intervals = append(intervals, d)
+		if len(intervals) == 3 {
+			provider.ApproveDevice(userCode, "alice")
+		}
+		ready := make(chan time.Time, 1)
+		ready <- time.Now()
+		return ready
+	}
+	provider.FailNext(oidctest.PathToken, http.StatusBadRequest, ErrorCodeSlowDown)
+	jwt, err := client.DeviceFlow(context.Background(), tokens.NewScopes("openid", "offline_access"), func(authorization *DeviceAuthorization) error {
+		if authorization.VerificationURIComplete == "" || authorization.ExpiresAt.IsZero() {
+			t.Fatalf("expected the complete verification URI and expiry but received '%v'", authorization)
+		}
+		userCode = authorization.UserCode
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected the device flow to complete but received '%v'", err)
+	}
+	if jwt.AccessToken() == "" || jwt.IDToken() == "" || jwt.RefreshToken() == "" {
+		t.Fatalf("expected access, ID and refresh tokens but received '%v'", jwt)
+	}
+	expected := []time.Duration{5 * time.Second, 10 * time.Second, 10 * time.Second}
+	if len(intervals) != len(expected) || intervals[0] != expected[0] || intervals[1] != expected[1] || intervals[2] != expected[2] {
+		t.Fatalf("expected the polling intervals '%v' but received '%v'", expected, intervals)
+	}
+	read := provider.KeySet().ReadSigned(jwt.AccessToken())
+	if sub, _ := read.Claims().GetClaimMustString("sub"); sub != "user-1" {
+		t.Fatalf("expected the token of the approving user but received '%s'", sub)
+	}
+
+	client.wait = func(d time.Duration) <-chan time.Time {
+		provider.DenyDevice(userCode)
+		return time.After(0)
+	}
+	_, err = client.DeviceFlow(context.Background(), nil, func(authorization *DeviceAuthorization) error {
+		userCode = authorization.UserCode
+		return nil
+	})
+	if responseError, ok := err.(*ResponseError); !ok || responseError.Code != "access_denied" {
+		t.Fatalf("expected the access_denied response error but received '%v'", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client.wait = func(d time.Duration) <-chan time.Time {
+		cancel()
+		return make(chan time.Time)
+	}
+	if _, err := client.DeviceFlow(ctx, nil, func(*DeviceAuthorization) error { return nil }); err != context.Canceled {
+		t.Fatalf("expected context.Canceled but received '%v'", err)
+	}
+	expired := &DeviceAuthorization{DeviceCode: "device", ExpiresAt: time.Now().Add(-time.Second)}
+	client.wait = func(d time.Duration) <-chan time.Time { return time.After(0) }
+	if _, err := client.DeviceToken(context.Background(), expired); err != ErrDeviceCodeExpired {
+		t.Fatalf("expected ErrDeviceCodeExpired but received '%v'", err)
+	}
+}
+
+type failingTransport struct {
+	failures int
+}
+
+func (t *failingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.URL.Path == oidctest.PathToken && t.failures > 0 {
+		t.failures--
+		return nil, errors.New("connection refused")
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestDeviceFlowConnectionBackoff(t *testing.T) {
+	provider, _ := oidctest.New(&oidctest.Config{Users: map[string]oidctest.User{
+		"alice": {Claims: tokens.Claims{"sub": "user-1"}},
+	}})
+	defer provider.Close()
+	openIDConfig, err := webfinger.ResolveOpenIDConfiguration(provider.Issuer())
+	if err != nil {
+		t.Fatalf("expected the OpenID configuration but received '%v'", err)
+	}
+	client := Discover(openIDConfig, &Config{
+		ClientID:     oidctest.DefaultClientID,
+		ClientSecret: oidctest.DefaultClientSecret,
+		HTTPClient:   &http.Client{Transport: &failingTransport{failures: 2}},
+	}).(*defaultClient)
+
+	userCode, intervals := "", []time.Duration{}
+	client.wait = func(d time.Duration) <-chan time.Time {
+		intervals = append(intervals, d)
+		provider.ApproveDevice(userCode, "alice")
+		return time.After(0)
+	}
+	_, err = client.DeviceFlow(context.Background(), nil, func(authorization *DeviceAuthorization) error {
+		userCode = authorization.UserCode
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected the device flow to recover from connection errors but received '%v'", err)
+	}
+	expected := []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second}
+	if len(intervals) != len(expected) || intervals[0] != expected[0] || intervals[1] != expected[1] || intervals[2] != expected[2] {
+		t.Fatalf("expected the polling intervals '%v' but received '%v'", expected, intervals)
+	}
+
+	client.config.HTTPClient = &http.Client{Transport: &failingTransport{failures: 6}}
+	intervals = []time.Duration{}
+	_, err = client.DeviceFlow(context.Background(), nil, func(authorization *DeviceAuthorization) error {
+		userCode = authorization.UserCode
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected the device flow to recover from connection errors but received '%v'", err)
+	}
+	if len(intervals) != 7 || intervals[4] != time.Minute || intervals[5] != time.Minute || intervals[6] != time.Minute {
+		t.Fatalf("expected the backoff to be capped at a minute but received '%v'", intervals)
+	}
+
+	if _, err := client.DeviceFlow(context.Background(), nil, nil); err != ErrPromptRequired {
+		t.Fatalf("expected ErrPromptRequired but received '%v'", err)
+	}
+}
+
+func TestDeviceTokenWaitsUntilExpiry(t *testing.T) {
+	client := New(&Config{
+		HTTPClient:    &http.Client{Transport: &failingTransport{failures: 1000}},
+		TokenEndpoint: "http://127.0.0.1" + oidctest.PathToken,
+	}).(*defaultClient)
+	waits := []time.Duration{}
+	client.wait = func(d time.Duration) <-chan time.Time {
+		waits = append(waits, d)
+		return time.After(d)
+	}
+	authorization := &DeviceAuthorization{DeviceCode: "device", ExpiresAt: time.Now().Add(100 * time.Millisecond), Interval: 5}
+	if _, err := client.DeviceToken(context.Background(), authorization); err != ErrDeviceCodeExpired {
+		t.Fatalf("expected ErrDeviceCodeExpired but received '%v'", err)
+	}
+	if len(waits) != 1 || waits[0] > 100*time.Millisecond {
+		t.Fatalf("expected a single wait until the device code expires but received '%v'", waits)
+	}
+}
+
+func TestDeviceAuthorizationWithoutExpiresIn(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"device_code":"device","user_code":"BCDF-GHJK","verification_uri":"https://example.com/device"}`))
+	}))
+	defer server.Close()
+	client := New(&Config{ClientID: "client", DeviceAuthorizationEndpoint: server.URL})
+	if _, err := client.DeviceAuthorization(context.Background(), nil); err != ErrInvalidResponse {
+		t.Fatalf("expected ErrInvalidResponse but received '%v'", err)
+	}
+}
@@ -0,0 +1,57 @@
+package tokenclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/radekg/app-kit-tokens/dpop"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func TestDPoPTokenRequest(t *testing.T) {
+	proofer, _ := dpop.NewProofer(jose.ES256)
+	verifier := dpop.NewVerifier(&dpop.VerifierConfig{Nonce: func(nonce string) bool { return nonce == "server-nonce" }})
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		proof, err := verifier.Verify(r.Header.Get("DPoP"), r.Method, "http://"+r.Host+r.URL.Path, "")
+		if err == dpop.ErrUseNonce {
+			w.Header().Set(dpop.NonceHeader, "server-nonce")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": dpop.ErrorCodeUseNonce})
+			return
+		}
+		if err != nil || r.PostFormValue("subject_token") != "subject" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_dpop_proof"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":      "bound-to-" + proof.Thumbprint,
+			"issued_token_type": TokenTypeAccessToken,
+			"token_type":        "DPoP",
+		})
+	}))
+	defer server.Close()
+
+	client := New(&Config{
+		ClientID:      "client",
+		ClientSecret:  "secret",
+		HTTPClient:    &http.Client{Transport: dpop.NewTransport(proofer, nil, nil)},
+		TokenEndpoint: server.URL,
+	})
+	exchanged, err := client.Exchange(context.Background(), &ExchangeRequest{SubjectToken: "subject", SubjectTokenType: TokenTypeAccessToken})
+	if err != nil {
+		t.Fatalf("expected the DPoP bound token but received '%v'", err)
+	}
+	if requests != 2 {
+		t.Fatalf("expected the token request to be retried with the nonce but received '%d' requests", requests)
+	}
+	if exchanged.TokenType() != "DPoP" || exchanged.AccessToken() != "bound-to-"+proofer.Thumbprint() {
+		t.Fatalf("expected the token bound to the proof key but received '%s' '%s'", exchanged.TokenType(), exchanged.AccessToken())
+	}
+}
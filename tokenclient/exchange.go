@@ -0,0 +1,124 @@
+package tokenclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+
+	"github.com/radekg/app-kit-tokens/tokens"
+)
+
+var (
+	// ErrActorTokenTypeRequired indicates an actor token without the token type.
+	ErrActorTokenTypeRequired = errActorTokenTypeRequired()
+	// ErrSubjectTokenRequired indicates an exchange request without the subject token or its type.
+	ErrSubjectTokenRequired = errSubjectTokenRequired()
+)
+
+func errActorTokenTypeRequired() error { return errors.New("actor token type required") }
+func errSubjectTokenRequired() error {
+	return errors.New("subject token and subject token type required")
+}
+
+// GrantTypeTokenExchange is the RFC 8693 grant type.
+const GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+// RFC 8693 token type identifiers:
+const (
+	TokenTypeAccessToken  = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeIDToken      = "urn:ietf:params:oauth:token-type:id_token"
+	TokenTypeJWT          = "urn:ietf:params:oauth:token-type:jwt"
+	TokenTypeRefreshToken = "urn:ietf:params:oauth:token-type:refresh_token"
+	TokenTypeSAML1        = "urn:ietf:params:oauth:token-type:saml1"
+	TokenTypeSAML2        = "urn:ietf:params:oauth:token-type:saml2"
+)
+
+// ExchangeRequest represents the RFC 8693 token exchange request.
+type ExchangeRequest struct {
+	// ActorToken: the token of the acting party for delegation, optional.
+	ActorToken string
+	// ActorTokenType: the type of the actor token, required with the actor token.
+	ActorTokenType string
+	// Audience: the logical names of the target services, optional.
+	Audience []string
+	// RequestedTokenType: the type of the requested token, optional.
+	RequestedTokenType string
+	// Resource: the URIs of the target services, optional.
+	Resource []string
+	// Scope: the scopes of the requested token, optional.
+	Scope tokens.Scopes
+	// SubjectToken: the token of the party on behalf of whom the request is made.
+	SubjectToken string
+	// SubjectTokenType: the type of the subject token.
+	SubjectTokenType string
+}
+
+// ExchangedToken represents the token issued by the token exchange.
+// The issued token is returned by AccessToken() regardless of its type.
+type ExchangedToken interface {
+	tokens.JWT
+	// IssuedTokenType returns the type of the issued token.
+	IssuedTokenType() string
+}
+
+type defaultExchangedToken struct {
+	tokens.JWT
+	issuedTokenType string
+}
+
+func (t *defaultExchangedToken) IssuedTokenType() string {
+	return t.issuedTokenType
+}
+
+func (c *defaultClient) Exchange(ctx context.Context, request *ExchangeRequest) (ExchangedToken, error) {
+	if c.config.TokenEndpoint == "" {
+		return nil, ErrNoTokenEndpoint
+	}
+	if request.SubjectToken == "" || request.SubjectTokenType == "" {
+		return nil, ErrSubjectTokenRequired
+	}
+	form := url.Values{
+		"grant_type":         {GrantTypeTokenExchange},
+		"subject_token":      {request.SubjectToken},
+		"subject_token_type": {request.SubjectTokenType},
+	}
+	if request.ActorToken != "" {
+		if request.ActorTokenType == "" {
+			return nil, ErrActorTokenTypeRequired
+		}
+		form.Set("actor_token", request.ActorToken)
+		form.Set("actor_token_type", request.ActorTokenType)
+	}
+	for _, audience := range request.Audience {
+		form.Add("audience", audience)
+	}
+	for _, resource := range request.Resource {
+		form.Add("resource", resource)
+	}
+	if len(request.Scope) > 0 {
+		form.Set("scope", request.Scope.String())
+	}
+	if request.RequestedTokenType != "" {
+		form.Set("requested_token_type", request.RequestedTokenType)
+	}
+
+	body, err := c.post(ctx, c.config.TokenEndpoint, form)
+	if err != nil {
+		return nil, err
+	}
+	jwt, err := tokens.DefaultJWT(body)
+	if err != nil {
+		return nil, err
+	}
+	issued := struct {
+		IssuedTokenType string `json:"issued_token_type"`
+	}{}
+	if err := json.Unmarshal(body, &issued); err != nil {
+		return nil, err
+	}
+	if jwt.AccessToken() == "" || jwt.TokenType() == "" || issued.IssuedTokenType == "" {
+		return nil, ErrInvalidResponse
+	}
+	return &defaultExchangedToken{JWT: jwt, issuedTokenType: issued.IssuedTokenType}, nil
+}
@@ -0,0 +1,78 @@
+package tokenclient
+
+import (
+	"context"
+	"testing"
+
+	"github.com/radekg/app-kit-tokens/oidctest"
+	"github.com/radekg/app-kit-tokens/tokens"
+	"github.com/radekg/app-kit-tokens/webfinger"
+)
+
+func TestExchange(t *testing.T) {
+	provider, err := oidctest.New(nil)
+	if err != nil {
+		t.Fatalf("expected provider but received '%v'", err)
+	}
+	defer provider.Close()
+	openIDConfig, err := webfinger.ResolveOpenIDConfiguration(provider.Issuer())
+	if err != nil {
+		t.Fatalf("expected the OpenID configuration but received '%v'", err)
+	}
+	client := Discover(openIDConfig, &Config{ClientID: oidctest.DefaultClientID, ClientSecret: oidctest.DefaultClientSecret})
+
+	subjectToken, _ := provider.AccessToken("user@example.com", tokens.Claims{"scope": "orders:read orders:write"})
+	actorToken, _ := provider.AccessToken("gateway", nil)
+	exchanged, err := client.Exchange(context.Background(), &ExchangeRequest{
+		ActorToken:       actorToken,
+		ActorTokenType:   TokenTypeAccessToken,
+		Audience:         []string{"orders-service"},
+		Scope:            tokens.NewScopes("orders:read"),
+		SubjectToken:     subjectToken,
+		SubjectTokenType: TokenTypeAccessToken,
+	})
+	if err != nil {
+		t.Fatalf("expected the exchanged token but received '%v'", err)
+	}
+	if exchanged.IssuedTokenType() != TokenTypeAccessToken {
+		t.Fatalf("expected the access token type but received '%s'", exchanged.IssuedTokenType())
+	}
+	read := provider.KeySet().ReadSigned(exchanged.AccessToken())
+	if read.Error() != nil {
+		t.Fatalf("expected the exchanged token to verify but received '%v'", read.Error())
+	}
+	accessToken := tokens.DefaultAccessToken(read.Claims())
+	if sub, _ := accessToken.Sub(); sub != "user@example.com" {
+		t.Fatalf("expected the subject of the subject token but received '%s'", sub)
+	}
+	if audience, _ := accessToken.Audience(); !audience.Contains("orders-service") {
+		t.Fatalf("expected the requested audience but received '%v'", audience)
+	}
+	if scopes, _ := accessToken.Scopes(); !scopes.Has("orders:read") || scopes.Has("orders:write") {
+		t.Fatalf("expected the downscoped token but received '%v'", scopes)
+	}
+	chain, ok := accessToken.Act()
+	if !ok || len(chain) != 1 {
+		t.Fatalf("expected the actor chain but received '%v'", chain)
+	}
+	if sub, _ := chain[0].GetClaimMustString("sub"); sub != "gateway" {
+		t.Fatalf("expected the actor in the act claim but received '%v'", chain)
+	}
+
+	_, err = client.Exchange(context.Background(), &ExchangeRequest{
+		Scope:            tokens.NewScopes("orders:read", "orders:delete"),
+		SubjectToken:     subjectToken,
+		SubjectTokenType: TokenTypeAccessToken,
+	})
+	if responseError, ok := err.(*ResponseError); !ok || responseError.Code != "invalid_scope" {
+		t.Fatalf("expected the invalid_scope response error for an upscoped request but received '%v'", err)
+	}
+
+	_, err = client.Exchange(context.Background(), &ExchangeRequest{SubjectToken: "invalid", SubjectTokenType: TokenTypeJWT})
+	if responseError, ok := err.(*ResponseError); !ok || responseError.Code != "invalid_grant" {
+		t.Fatalf("expected the invalid_grant response error but received '%v'", err)
+	}
+	if _, err := client.Exchange(context.Background(), &ExchangeRequest{SubjectToken: subjectToken}); err != ErrSubjectTokenRequired {
+		t.Fatalf("expected ErrSubjectTokenRequired but received '%v'", err)
+	}
+}
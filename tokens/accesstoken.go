@@ -15,9 +15,16 @@ type AccessToken interface {
 	Iss() (string, bool)
 	Jti() (string, bool)
 	Scope() (string, bool)
+	Scopes() (Scopes, bool)
 	Sub() (string, bool)
 	// Common convenience properties:
+	// Act returns the RFC 8693 actor chain of delegated tokens: the current actor first,
+	// followed by the prior actors from the nested act claims.
+	Act() ([]Claims, bool)
 	Aud() (interface{}, bool)
+	Audience() (Audience, bool)
+	// Cnf returns the RFC 7800 confirmation claim of sender-constrained tokens.
+	Cnf() (Claims, bool)
 	Nbf() (int64, bool)
 	Typ() (string, bool)
 	// Other convenience methods:
@@ -29,9 +36,27 @@ type defaultAccessToken struct {
 	claims Claims
 }
 
+func (at *defaultAccessToken) Act() ([]Claims, bool) {
+	actor, ok := at.claims.getClaimsClaim("act")
+	if !ok {
+		return nil, false
+	}
+	chain := []Claims{}
+	for ok {
+		chain = append(chain, actor)
+		actor, ok = actor.getClaimsClaim("act")
+	}
+	return chain, true
+}
 func (at *defaultAccessToken) Aud() (interface{}, bool) {
 	return at.claims.GetClaim("aud")
 }
+func (at *defaultAccessToken) Audience() (Audience, bool) {
+	return at.claims.getAudienceClaim()
+}
+func (at *defaultAccessToken) Cnf() (Claims, bool) {
+	return at.claims.getClaimsClaim("cnf")
+}
 func (at *defaultAccessToken) ClientID() (string, bool) {
 	return at.claims.GetClaimMustString("client_id")
 }
@@ -51,14 +76,10 @@ func (at *defaultAccessToken) Nbf() (int64, bool) {
 	return at.claims.getInt64Claim("nbf")
 }
 func (at *defaultAccessToken) Scope() (string, bool) {
-	// ORY Hydra returns this claim as scp.
-	// Keycloak returns this as scope (as specified in the spec).
-	for _, claimName := range []string{"scp", "scope"} {
-		if at.claims.HasClaim(claimName) {
-			return at.claims.GetClaimMustString(claimName)
-		}
-	}
-	return "", false
+	return at.claims.getScopeClaim()
+}
+func (at *defaultAccessToken) Scopes() (Scopes, bool) {
+	return at.claims.getScopesClaim()
 }
 func (at *defaultAccessToken) Sub() (string, bool) {
 	return at.claims.GetClaimMustString("sub")
@@ -72,7 +93,7 @@ func (at *defaultAccessToken) Typ() (string, bool) {
 // DefaultAccessToken returns an instacne of the access token.
 // Call this function using claims returned from jwks.Validator.ValidateToken(string).
 func DefaultAccessToken(claims Claims) AccessToken {
-	return &defaultAccessToken{claims: claims}
+	return &defaultAccessToken{defaultToken: defaultToken{claims: claims}, claims: claims}
 }
 
 func defaultInsecureAccessToken(t *testing.T, rawToken string) (AccessToken, error) {
@@ -48,3 +48,26 @@ func TestParseKeycloakAccessToken(t *testing.T) {
 		t.Fatal("Expected scope to be non-empty")
 	}
 }
+
+func TestAccessTokenActorChain(t *testing.T) {
+	accessToken := DefaultAccessToken(Claims{
+		"sub": "user@example.com",
+		"act": map[string]interface{}{
+			"sub": "gateway",
+			"act": map[string]interface{}{"sub": "batch-job"},
+		},
+	})
+	chain, ok := accessToken.Act()
+	if !ok || len(chain) != 2 {
+		t.Fatalf("expected an actor chain of two but received '%v'", chain)
+	}
+	if sub, _ := chain[0].GetClaimMustString("sub"); sub != "gateway" {
+		t.Fatalf("expected the current actor first but received '%s'", sub)
+	}
+	if sub, _ := chain[1].GetClaimMustString("sub"); sub != "batch-job" {
+		t.Fatalf("expected the prior actor but received '%s'", sub)
+	}
+	if _, ok := DefaultAccessToken(Claims{"sub": "user@example.com"}).Act(); ok {
+		t.Fatal("expected no actor chain for a token without the act claim")
+	}
+}
@@ -0,0 +1,95 @@
+package tokens
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+var audienceType = reflect.TypeOf(Audience{})
+
+// Audience represents the aud claim.
+// The aud claim is either a single string or an array of strings,
+// both forms are normalised into a slice.
+type Audience []string
+
+// ParseAudience parses an audience given either as a string or as an array.
+func ParseAudience(value interface{}) (Audience, bool) {
+	switch tvalue := value.(type) {
+	case string:
+		if tvalue == "" {
+			return Audience{}, true
+		}
+		return Audience{tvalue}, true
+	case Audience:
+		return tvalue, true
+	case []string:
+		return Audience(tvalue), true
+	case []interface{}:
+		audience := Audience{}
+		for _, item := range tvalue {
+			stringItem, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			audience = append(audience, stringItem)
+		}
+		return audience, true
+	default:
+		return nil, false
+	}
+}
+
+// Contains checks if the audience contains the value.
+func (a Audience) Contains(value string) bool {
+	for _, item := range a {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsAll checks if the audience contains all of the values.
+func (a Audience) ContainsAll(values ...string) bool {
+	for _, value := range values {
+		if !a.Contains(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// ContainsAny checks if the audience contains any of the values.
+func (a Audience) ContainsAny(values ...string) bool {
+	for _, value := range values {
+		if a.Contains(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// UnmarshalJSON accepts both, a single string and an array.
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if value == nil {
+		*a = nil
+		return nil
+	}
+	audience, ok := ParseAudience(value)
+	if !ok {
+		return &json.UnmarshalTypeError{Value: string(data), Type: audienceType}
+	}
+	*a = audience
+	return nil
+}
+
+func (c Claims) getAudienceClaim() (Audience, bool) {
+	if value, ok := c["aud"]; ok {
+		return ParseAudience(value)
+	}
+	return nil, false
+}
@@ -0,0 +1,318 @@
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrClaimNotFound indicates a claim not present in the claims.
+	ErrClaimNotFound = errClaimNotFound()
+	// ErrDecodeTarget indicates a Decode target other than a non-nil pointer to a struct.
+	ErrDecodeTarget = errDecodeTarget()
+)
+
+func errClaimNotFound() error { return errors.New("claim not found") }
+func errDecodeTarget() error {
+	return errors.New("decode target must be a non-nil pointer to a struct")
+}
+
+// ClaimTypeError indicates a claim value not matching the requested type.
+type ClaimTypeError struct {
+	Claim    string
+	Expected string
+	Value    interface{}
+}
+
+func (e *ClaimTypeError) Error() string {
+	return fmt.Sprintf("claim '%s': expected %s, got %T", e.Claim, e.Expected, e.Value)
+}
+
+// GetString returns a string claim.
+func (c Claims) GetString(claim string) (string, error) {
+	value, ok := c[claim]
+	if !ok {
+		return "", ErrClaimNotFound
+	}
+	return convertString(claim, value)
+}
+
+// GetInt64 returns an integer claim.
+// A number with a fractional part or out of the int64 range is a type mismatch.
+func (c Claims) GetInt64(claim string) (int64, error) {
+	value, ok := c[claim]
+	if !ok {
+		return 0, ErrClaimNotFound
+	}
+	return convertInt64(claim, value)
+}
+
+// GetBool returns a boolean claim.
+func (c Claims) GetBool(claim string) (bool, error) {
+	value, ok := c[claim]
+	if !ok {
+		return false, ErrClaimNotFound
+	}
+	return convertBool(claim, value)
+}
+
+// GetStringSlice returns a string array claim.
+// A claim given as a single string is returned as a single item slice.
+func (c Claims) GetStringSlice(claim string) ([]string, error) {
+	value, ok := c[claim]
+	if !ok {
+		return nil, ErrClaimNotFound
+	}
+	return convertStringSlice(claim, value)
+}
+
+// GetTime returns a NumericDate claim as time.
+func (c Claims) GetTime(claim string) (time.Time, error) {
+	value, ok := c[claim]
+	if !ok {
+		return time.Time{}, ErrClaimNotFound
+	}
+	return convertTime(claim, value)
+}
+
+// GetClaims returns a JSON object claim.
+func (c Claims) GetClaims(claim string) (Claims, error) {
+	value, ok := c[claim]
+	if !ok {
+		return nil, ErrClaimNotFound
+	}
+	return convertClaims(claim, value)
+}
+
+// convertString converts the value of the claim to a string.
+// The claim name is reported in the ClaimTypeError.
+func convertString(claim string, value interface{}) (string, error) {
+	if stringValue, ok := value.(string); ok {
+		return stringValue, nil
+	}
+	return "", &ClaimTypeError{Claim: claim, Expected: "string", Value: value}
+}
+
+// convertInt64 converts the value of the claim to an integer.
+// A number with a fractional part or out of the int64 range is a type mismatch.
+func convertInt64(claim string, value interface{}) (int64, error) {
+	switch tvalue := value.(type) {
+	case float32, float64:
+		if float64Value, _ := toFloat64(tvalue); float64Value == math.Trunc(float64Value) &&
+			float64Value >= math.MinInt64 && float64Value < math.MaxInt64 {
+			return int64(float64Value), nil
+		}
+	case uint:
+		if uint64(tvalue) <= math.MaxInt64 {
+			return int64(tvalue), nil
+		}
+	case uint64:
+		if tvalue <= math.MaxInt64 {
+			return int64(tvalue), nil
+		}
+	default:
+		if int64Value, ok := toInt64(value); ok {
+			return int64Value, nil
+		}
+	}
+	return 0, &ClaimTypeError{Claim: claim, Expected: "int64", Value: value}
+}
+
+// convertBool converts the value of the claim to a boolean.
+func convertBool(claim string, value interface{}) (bool, error) {
+	if boolValue, ok := value.(bool); ok {
+		return boolValue, nil
+	}
+	return false, &ClaimTypeError{Claim: claim, Expected: "bool", Value: value}
+}
+
+// convertStringSlice converts the value of the claim to a string slice.
+// A single string is returned as a single item slice.
+func convertStringSlice(claim string, value interface{}) ([]string, error) {
+	if stringValue, ok := value.(string); ok {
+		return []string{stringValue}, nil
+	}
+	if sliceValue, ok := toStringSlice(value); ok {
+		return sliceValue, nil
+	}
+	return nil, &ClaimTypeError{Claim: claim, Expected: "[]string", Value: value}
+}
+
+// convertTime converts the NumericDate value of the claim to time.
+func convertTime(claim string, value interface{}) (time.Time, error) {
+	if float64Value, ok := toFloat64(value); ok {
+		return numericDate(float64Value), nil
+	}
+	return time.Time{}, &ClaimTypeError{Claim: claim, Expected: "NumericDate", Value: value}
+}
+
+// convertClaims converts the JSON object value of the claim to claims.
+func convertClaims(claim string, value interface{}) (Claims, error) {
+	if claimsValue, ok := toClaims(value); ok {
+		return claimsValue, nil
+	}
+	return nil, &ClaimTypeError{Claim: claim, Expected: "object", Value: value}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Decode maps the claims onto the struct pointed to by v.
+// The claim name is taken from the claim tag, then from the json tag, then from the field name.
+// Fields tagged with "-" are skipped and claims not present in the claims leave the fields untouched.
+// Nested objects decode into structs and maps, NumericDate claims decode into time.Time
+// and a single string decodes into a string slice.
+func (c Claims) Decode(v interface{}) error {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Ptr || target.IsNil() || target.Elem().Kind() != reflect.Struct {
+		return ErrDecodeTarget
+	}
+	return decodeStruct("", c, target.Elem())
+}
+
+func decodeStruct(path string, claims Claims, target reflect.Value) error {
+	targetType := target.Type()
+	for i := 0; i < targetType.NumField(); i++ {
+		field := targetType.Field(i)
+		name := claimFieldName(field)
+		if name == "-" {
+			continue
+		}
+		// exported fields of embedded structs are decoded as if they were in the outer struct:
+		if name == "" && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := decodeStruct(path, claims, target.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		value, ok := claims[name]
+		if !ok {
+			continue
+		}
+		if err := decodeValue(path+name, value, target.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func claimFieldName(field reflect.StructField) string {
+	for _, tagName := range []string{"claim", "json"} {
+		if tag, ok := field.Tag.Lookup(tagName); ok {
+			if name := strings.Split(tag, ",")[0]; name != "" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+func decodeValue(path string, value interface{}, target reflect.Value) error {
+	if value == nil {
+		return nil
+	}
+	targetType := target.Type()
+	if targetType == timeType {
+		float64Value, ok := toFloat64(value)
+		if !ok {
+			return &ClaimTypeError{Claim: path, Expected: "NumericDate", Value: value}
+		}
+		target.Set(reflect.ValueOf(numericDate(float64Value)))
+		return nil
+	}
+	if reflect.TypeOf(value).AssignableTo(targetType) {
+		target.Set(reflect.ValueOf(value))
+		return nil
+	}
+
+	switch targetType.Kind() {
+	case reflect.Ptr:
+		item := reflect.New(targetType.Elem())
+		if err := decodeValue(path, value, item.Elem()); err != nil {
+			return err
+		}
+		target.Set(item)
+		return nil
+	case reflect.String:
+		if stringValue, ok := value.(string); ok {
+			target.SetString(stringValue)
+			return nil
+		}
+	case reflect.Bool:
+		if boolValue, ok := value.(bool); ok {
+			target.SetBool(boolValue)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// the range is checked before the conversion, converting an out of range float is undefined:
+		limit := math.Ldexp(1, targetType.Bits()-1)
+		if float64Value, ok := toFloat64(value); ok && float64Value == math.Trunc(float64Value) &&
+			float64Value >= -limit && float64Value < limit {
+			target.SetInt(int64(float64Value))
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		limit := math.Ldexp(1, targetType.Bits())
+		if float64Value, ok := toFloat64(value); ok && float64Value == math.Trunc(float64Value) &&
+			float64Value >= 0 && float64Value < limit {
+			target.SetUint(uint64(float64Value))
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if float64Value, ok := toFloat64(value); ok {
+			target.SetFloat(float64Value)
+			return nil
+		}
+	case reflect.Slice:
+		items, ok := value.([]interface{})
+		if !ok {
+			// a string-or-array claim given as a single string:
+			if _, isString := value.(string); !isString {
+				break
+			}
+			items = []interface{}{value}
+		}
+		slice := reflect.MakeSlice(targetType, len(items), len(items))
+		for index, item := range items {
+			if err := decodeValue(fmt.Sprintf("%s[%d]", path, index), item, slice.Index(index)); err != nil {
+				return err
+			}
+		}
+		target.Set(slice)
+		return nil
+	case reflect.Map:
+		object, ok := toClaims(value)
+		if !ok || targetType.Key().Kind() != reflect.String {
+			break
+		}
+		mapValue := reflect.MakeMapWithSize(targetType, len(object))
+		for key, item := range object {
+			itemValue := reflect.New(targetType.Elem()).Elem()
+			if err := decodeValue(path+"."+key, item, itemValue); err != nil {
+				return err
+			}
+			mapValue.SetMapIndex(reflect.ValueOf(key).Convert(targetType.Key()), itemValue)
+		}
+		target.Set(mapValue)
+		return nil
+	case reflect.Struct:
+		if object, ok := toClaims(value); ok {
+			return decodeStruct(path+".", object, target)
+		}
+	}
+	return &ClaimTypeError{Claim: path, Expected: targetType.String(), Value: value}
+}
+
+func numericDate(value float64) time.Time {
+	seconds, fraction := math.Modf(value)
+	return time.Unix(int64(seconds), int64(fraction*1e9))
+}
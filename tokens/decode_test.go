@@ -0,0 +1,147 @@
+package tokens
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+type testRealmAccess struct {
+	Roles []string `claim:"roles"`
+}
+
+type testStandardClaims struct {
+	Issuer  string    `json:"iss"`
+	Subject string    `json:"sub"`
+	Expiry  time.Time `claim:"exp"`
+}
+
+type testKeycloakClaims struct {
+	testStandardClaims
+	Audience       []string                   `claim:"aud"`
+	EmailVerified  bool                       `claim:"email_verified"`
+	RealmAccess    *testRealmAccess           `claim:"realm_access"`
+	ResourceAccess map[string]testRealmAccess `claim:"resource_access"`
+	AllowedOrigins []string                   `claim:"allowed-origins"`
+	NotPresent     string                     `claim:"not_present"`
+	Skipped        string                     `claim:"-"`
+	Raw            interface{}                `claim:"session_state"`
+}
+
+func TestClaimsDecode(t *testing.T) {
+	keycloakToken, _ := defaultInsecureAccessToken(t, tokenKeycloak)
+	decoded := &testKeycloakClaims{NotPresent: "default"}
+	if err := keycloakToken.RawClaims().Decode(decoded); err != nil {
+		t.Fatalf("Expected claims to decode but received '%v'", err)
+	}
+	if decoded.Issuer != "http://127.0.0.1:8081/auth/realms/multi-customer" {
+		t.Fatalf("Expected embedded struct issuer different than received '%s'", decoded.Issuer)
+	}
+	if !decoded.Expiry.Equal(time.Unix(1618151228, 0)) {
+		t.Fatalf("Expected expiry different than received '%v'", decoded.Expiry)
+	}
+	if len(decoded.Audience) != 1 || decoded.Audience[0] != "account" {
+		t.Fatalf("Expected audience from a single string to be '[account]' but received '%v'", decoded.Audience)
+	}
+	if !decoded.EmailVerified {
+		t.Fatal("Expected email verified to be true")
+	}
+	if decoded.RealmAccess == nil || len(decoded.RealmAccess.Roles) != 2 {
+		t.Fatalf("Expected nested realm access roles but received '%v'", decoded.RealmAccess)
+	}
+	if len(decoded.ResourceAccess["account"].Roles) != 3 {
+		t.Fatalf("Expected nested resource access roles but received '%v'", decoded.ResourceAccess)
+	}
+	if decoded.NotPresent != "default" {
+		t.Fatalf("Expected missing claim to leave the field untouched but received '%s'", decoded.NotPresent)
+	}
+	if decoded.Raw != "3b65e46a-a6ad-4dff-8584-49b0e6a21a23" {
+		t.Fatalf("Expected raw session state different than received '%v'", decoded.Raw)
+	}
+}
+
+func TestClaimsDecodeErrors(t *testing.T) {
+	if err := (Claims{}).Decode(testStandardClaims{}); err != ErrDecodeTarget {
+		t.Fatalf("Expected decode target error but received '%v'", err)
+	}
+	err := Claims{"realm_access": map[string]interface{}{"roles": []interface{}{"a", 1}}}.Decode(&testKeycloakClaims{})
+	typeErr, ok := err.(*ClaimTypeError)
+	if !ok {
+		t.Fatalf("Expected claim type error but received '%v'", err)
+	}
+	if typeErr.Claim != "realm_access.roles[1]" {
+		t.Fatalf("Expected claim type error path different than received '%s'", typeErr.Claim)
+	}
+	target := struct {
+		Count int8 `claim:"count"`
+	}{}
+	if err := (Claims{"count": float64(300)}).Decode(&target); err == nil {
+		t.Fatal("Expected overflowing integer not to decode")
+	}
+	wide := struct {
+		Signed   int64  `claim:"signed"`
+		Unsigned uint64 `claim:"unsigned"`
+	}{}
+	if err := (Claims{"signed": 1e19}).Decode(&wide); err == nil || wide.Signed != 0 {
+		t.Fatalf("Expected 1e19 not to decode into int64 but received '%d', '%v'", wide.Signed, err)
+	}
+	if err := (Claims{"unsigned": 1e20}).Decode(&wide); err == nil || wide.Unsigned != 0 {
+		t.Fatalf("Expected 1e20 not to decode into uint64 but received '%d', '%v'", wide.Unsigned, err)
+	}
+	if err := (Claims{"signed": -9.223372036854775808e18}).Decode(&wide); err != nil || wide.Signed != math.MinInt64 {
+		t.Fatalf("Expected the smallest int64 to decode but received '%d', '%v'", wide.Signed, err)
+	}
+}
+
+func TestClaimsTypedGetters(t *testing.T) {
+	claims := Claims{
+		"exp":    float64(1618151228),
+		"flag":   true,
+		"name":   "value",
+		"list":   []interface{}{"a", "b"},
+		"single": "a",
+		"object": map[string]interface{}{"key": "value"},
+	}
+	if value, err := claims.GetInt64("exp"); err != nil || value != 1618151228 {
+		t.Fatalf("Expected int64 claim but received '%v', '%v'", value, err)
+	}
+	if value, err := claims.GetTime("exp"); err != nil || value.Unix() != 1618151228 {
+		t.Fatalf("Expected time claim but received '%v', '%v'", value, err)
+	}
+	if value, err := claims.GetBool("flag"); err != nil || !value {
+		t.Fatalf("Expected bool claim but received '%v', '%v'", value, err)
+	}
+	if value, err := claims.GetString("name"); err != nil || value != "value" {
+		t.Fatalf("Expected string claim but received '%v', '%v'", value, err)
+	}
+	if value, err := claims.GetStringSlice("list"); err != nil || len(value) != 2 {
+		t.Fatalf("Expected string slice claim but received '%v', '%v'", value, err)
+	}
+	if value, err := claims.GetStringSlice("single"); err != nil || len(value) != 1 {
+		t.Fatalf("Expected string slice from a single string but received '%v', '%v'", value, err)
+	}
+	if value, err := claims.GetClaims("object"); err != nil || value["key"] != "value" {
+		t.Fatalf("Expected object claim but received '%v', '%v'", value, err)
+	}
+	if _, err := claims.GetString("exp"); err == nil {
+		t.Fatal("Expected type mismatch error for a number read as string")
+	}
+	if _, err := claims.GetInt64("name"); err == nil {
+		t.Fatal("Expected type mismatch error for a string read as int64")
+	}
+	if _, err := claims.GetBool("missing"); err != ErrClaimNotFound {
+		t.Fatalf("Expected claim not found error but received '%v'", err)
+	}
+	fractional := Claims{"value": 1.5, "large": 1e19}
+	for _, claim := range []string{"value", "large"} {
+		if _, err := fractional.GetInt64(claim); err == nil {
+			t.Fatalf("Expected type mismatch error for '%s' read as int64", claim)
+		} else if _, ok := err.(*ClaimTypeError); !ok {
+			t.Fatalf("Expected ClaimTypeError but received '%v'", err)
+		}
+	}
+	mixed := Claims{"list": []interface{}{"a", 1}}
+	if _, err := mixed.GetStringSlice("list"); err == nil {
+		t.Fatal("Expected type mismatch error for an array with non-string values")
+	}
+}
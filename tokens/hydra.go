@@ -0,0 +1,134 @@
+package tokens
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// HydraAccessToken represents an ORY Hydra access token.
+// It exposes Hydra specific claims on top of the default access token properties.
+type HydraAccessToken interface {
+	AccessToken
+	// Hydra specific properties:
+	Ext() (Claims, bool)
+	Scp() ([]string, bool)
+}
+
+type defaultHydraAccessToken struct {
+	AccessToken
+	claims Claims
+}
+
+// Ext returns the custom session data Hydra places under the ext claim.
+func (ht *defaultHydraAccessToken) Ext() (Claims, bool) {
+	return ht.claims.getClaimsClaim("ext")
+}
+
+// Scp returns the scopes as a slice. Hydra returns the scopes as an scp array,
+// a space-delimited scope claim is used if scp is not present.
+func (ht *defaultHydraAccessToken) Scp() ([]string, bool) {
+	if ht.claims.HasClaim("scp") {
+		return ht.claims.getStringSliceClaim("scp")
+	}
+	if scope, ok := ht.claims.GetClaimMustString("scope"); ok {
+		return strings.Fields(scope), true
+	}
+	return nil, false
+}
+
+// DefaultHydraAccessToken returns an ORY Hydra view over the access token.
+func DefaultHydraAccessToken(accessToken AccessToken) HydraAccessToken {
+	return &defaultHydraAccessToken{AccessToken: accessToken, claims: accessToken.RawClaims()}
+}
+
+// HydraIntrospection represents the ORY Hydra token introspection response.
+type HydraIntrospection interface {
+	// RFC 7662 properties:
+	Active() bool
+	Aud() []string
+	ClientID() string
+	Exp() int64
+	Iat() int64
+	Iss() string
+	Nbf() int64
+	Scope() string
+	Sub() string
+	TokenType() string
+	Username() string
+	// Hydra specific properties:
+	Ext() Claims
+	ObfuscatedSubject() string
+	Scp() []string
+	TokenUse() string
+}
+
+type defaultHydraIntrospection struct {
+	ActiveValue            bool     `json:"active"`
+	AudValue               Audience `json:"aud"`
+	ClientIDValue          string   `json:"client_id"`
+	ExpValue               int64    `json:"exp"`
+	ExtValue               Claims   `json:"ext"`
+	IatValue               int64    `json:"iat"`
+	IssValue               string   `json:"iss"`
+	NbfValue               int64    `json:"nbf"`
+	ObfuscatedSubjectValue string   `json:"obfuscated_subject"`
+	ScopeValue             string   `json:"scope"`
+	SubValue               string   `json:"sub"`
+	TokenTypeValue         string   `json:"token_type"`
+	TokenUseValue          string   `json:"token_use"`
+	UsernameValue          string   `json:"username"`
+}
+
+func (hi *defaultHydraIntrospection) Active() bool {
+	return hi.ActiveValue
+}
+func (hi *defaultHydraIntrospection) Aud() []string {
+	return hi.AudValue
+}
+func (hi *defaultHydraIntrospection) ClientID() string {
+	return hi.ClientIDValue
+}
+func (hi *defaultHydraIntrospection) Exp() int64 {
+	return hi.ExpValue
+}
+func (hi *defaultHydraIntrospection) Iat() int64 {
+	return hi.IatValue
+}
+func (hi *defaultHydraIntrospection) Iss() string {
+	return hi.IssValue
+}
+func (hi *defaultHydraIntrospection) Nbf() int64 {
+	return hi.NbfValue
+}
+func (hi *defaultHydraIntrospection) Scope() string {
+	return hi.ScopeValue
+}
+func (hi *defaultHydraIntrospection) Sub() string {
+	return hi.SubValue
+}
+func (hi *defaultHydraIntrospection) TokenType() string {
+	return hi.TokenTypeValue
+}
+func (hi *defaultHydraIntrospection) Username() string {
+	return hi.UsernameValue
+}
+func (hi *defaultHydraIntrospection) Ext() Claims {
+	return hi.ExtValue
+}
+func (hi *defaultHydraIntrospection) ObfuscatedSubject() string {
+	return hi.ObfuscatedSubjectValue
+}
+func (hi *defaultHydraIntrospection) Scp() []string {
+	return strings.Fields(hi.ScopeValue)
+}
+func (hi *defaultHydraIntrospection) TokenUse() string {
+	return hi.TokenUseValue
+}
+
+// DefaultHydraIntrospection tries to parse an ORY Hydra introspection response from bytes.
+func DefaultHydraIntrospection(rawData []byte) (HydraIntrospection, error) {
+	introspection := &defaultHydraIntrospection{}
+	unmarshalErr := json.NewDecoder(bytes.NewReader(rawData)).Decode(introspection)
+	return introspection, unmarshalErr
+}
@@ -0,0 +1,97 @@
+package tokens
+
+import "testing"
+
+func TestHydraAccessToken(t *testing.T) {
+	accessToken, parseErr := defaultInsecureAccessToken(t, tokenHydra)
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	scope, _ := accessToken.Scope()
+	if scope != "openid offline" {
+		t.Fatalf("Expected scope to be 'openid offline' but received '%s'", scope)
+	}
+
+	hydraToken := DefaultHydraAccessToken(accessToken)
+	scp, scpOK := hydraToken.Scp()
+	if !scpOK {
+		t.Fatal("Expected scp to be OK")
+	}
+	if len(scp) != 2 || scp[0] != "openid" || scp[1] != "offline" {
+		t.Fatalf("Expected scp to be '[openid offline]' but received '%v'", scp)
+	}
+	ext, extOK := hydraToken.Ext()
+	if !extOK {
+		t.Fatal("Expected ext to be OK")
+	}
+	if len(ext) != 0 {
+		t.Fatalf("Expected ext to be empty but received '%v'", ext)
+	}
+	if clientID, _ := hydraToken.ClientID(); clientID != "my-client" {
+		t.Fatalf("Expected client_id to be 'my-client' but received '%s'", clientID)
+	}
+}
+
+func TestHydraAccessTokenExt(t *testing.T) {
+	hydraToken := DefaultHydraAccessToken(DefaultAccessToken(Claims{
+		"ext":   map[string]interface{}{"tenant": "tenant-1"},
+		"scope": "openid offline",
+	}))
+	ext, _ := hydraToken.Ext()
+	if tenant, _ := ext.GetClaimMustString("tenant"); tenant != "tenant-1" {
+		t.Fatalf("Expected ext tenant to be 'tenant-1' but received '%s'", tenant)
+	}
+	if scp, _ := hydraToken.Scp(); len(scp) != 2 {
+		t.Fatalf("Expected scp to be '[openid offline]' but received '%v'", scp)
+	}
+}
+
+func TestHydraIntrospection(t *testing.T) {
+	introspection, err := DefaultHydraIntrospection([]byte(`{
+		"active": true,
+		"aud": ["api"],
+		"client_id": "my-client",
+		"exp": 1618153201,
+		"ext": {"tenant": "tenant-1"},
+		"iat": 1618149601,
+		"iss": "http://127.0.0.1:4444/",
+		"obfuscated_subject": "obfuscated",
+		"scope": "openid offline",
+		"sub": "subject",
+		"token_type": "Bearer",
+		"token_use": "access_token"
+	}`))
+	if err != nil {
+		t.Fatalf("expected introspection to parse but received '%v'", err)
+	}
+	if !introspection.Active() {
+		t.Fatal("Expected introspection to be active")
+	}
+	if tenant, _ := introspection.Ext().GetClaimMustString("tenant"); tenant != "tenant-1" {
+		t.Fatalf("Expected ext tenant to be 'tenant-1' but received '%s'", tenant)
+	}
+	if len(introspection.Scp()) != 2 {
+		t.Fatalf("Expected 2 scopes but received '%v'", introspection.Scp())
+	}
+	if introspection.TokenUse() != "access_token" {
+		t.Fatalf("Expected token use to be 'access_token' but received '%s'", introspection.TokenUse())
+	}
+	if introspection.ObfuscatedSubject() != "obfuscated" {
+		t.Fatalf("Expected obfuscated subject different than received '%s'", introspection.ObfuscatedSubject())
+	}
+	if len(introspection.Aud()) != 1 || introspection.Aud()[0] != "api" {
+		t.Fatalf("Expected audience to be '[api]' but received '%v'", introspection.Aud())
+	}
+
+	introspection, err = DefaultHydraIntrospection([]byte(`{"active": true, "aud": "api"}`))
+	if err != nil {
+		t.Fatalf("expected introspection with a string audience to parse but received '%v'", err)
+	}
+	if len(introspection.Aud()) != 1 || introspection.Aud()[0] != "api" {
+		t.Fatalf("Expected audience to be '[api]' but received '%v'", introspection.Aud())
+	}
+	if _, err := DefaultHydraIntrospection([]byte(`{"aud": ["api", 1]}`)); err == nil {
+		t.Fatal("Expected audience with non-string values not to parse")
+	}
+}
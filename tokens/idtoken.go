@@ -38,6 +38,9 @@ type IDToken interface {
 	CHash() (string, bool)
 	// https://openid.net/specs/openid-connect-core-1_0.html#SelfIssuedValidation
 	SubJWK() (interface{}, bool)
+
+	// Other convenience methods:
+	RawClaims() Claims
 }
 
 type defaultIDToken struct {
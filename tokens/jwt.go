@@ -3,6 +3,7 @@ package tokens
 import (
 	"bytes"
 	"encoding/json"
+	"strings"
 )
 
 // JWT is a JWT
@@ -16,6 +17,7 @@ type JWT interface {
 	NotBeforePolicy() int64
 	SessionState() string
 	Scope() string
+	Scopes() Scopes
 }
 
 type defaultJWT struct {
@@ -57,6 +59,10 @@ func (j *defaultJWT) SessionState() string {
 func (j *defaultJWT) Scope() string {
 	return j.ScopeValue
 }
+func (j *defaultJWT) Scopes() Scopes {
+	scopes, _ := ParseScopes(j.ScopeValue)
+	return scopes
+}
 
 // DefaultJWT tries to parse a JWT from bytes using the default implementation.
 func DefaultJWT(rawData []byte) (JWT, error) {
@@ -64,3 +70,9 @@ func DefaultJWT(rawData []byte) (JWT, error) {
 	unmarshalErr := json.NewDecoder(bytes.NewReader(rawData)).Decode(jwt)
 	return jwt, unmarshalErr
 }
+
+// IsEncrypted returns true if the raw token uses the JWE compact serialization,
+// for example an encrypted ID token, false for the JWS compact serialization.
+func IsEncrypted(rawToken string) bool {
+	return strings.Count(rawToken, ".") == 4
+}
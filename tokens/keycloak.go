@@ -0,0 +1,95 @@
+package tokens
+
+import "strings"
+
+// KeycloakAccessToken represents a Keycloak access token.
+// It exposes Keycloak specific claims on top of the default access token properties.
+type KeycloakAccessToken interface {
+	AccessToken
+	// Keycloak specific properties:
+	Acr() (string, bool)
+	AllowedOrigins() ([]string, bool)
+	Azp() (string, bool)
+	ClientRoles(clientID string) ([]string, bool)
+	Groups() ([]string, bool)
+	RealmRoles() ([]string, bool)
+	SessionState() (string, bool)
+	// Convenience checks:
+	HasClientRole(clientID, role string) bool
+	HasGroup(group string) bool
+	HasRealmRole(role string) bool
+}
+
+type defaultKeycloakAccessToken struct {
+	AccessToken
+	claims Claims
+}
+
+func (kt *defaultKeycloakAccessToken) Acr() (string, bool) {
+	return kt.claims.GetClaimMustString("acr")
+}
+func (kt *defaultKeycloakAccessToken) AllowedOrigins() ([]string, bool) {
+	return kt.claims.getStringSliceClaim("allowed-origins")
+}
+func (kt *defaultKeycloakAccessToken) Azp() (string, bool) {
+	return kt.claims.GetClaimMustString("azp")
+}
+func (kt *defaultKeycloakAccessToken) ClientRoles(clientID string) ([]string, bool) {
+	resourceAccess, ok := kt.claims.getClaimsClaim("resource_access")
+	if !ok {
+		return nil, false
+	}
+	clientAccess, ok := resourceAccess.getClaimsClaim(clientID)
+	if !ok {
+		return nil, false
+	}
+	return clientAccess.getStringSliceClaim("roles")
+}
+func (kt *defaultKeycloakAccessToken) Groups() ([]string, bool) {
+	return kt.claims.getStringSliceClaim("groups")
+}
+func (kt *defaultKeycloakAccessToken) RealmRoles() ([]string, bool) {
+	realmAccess, ok := kt.claims.getClaimsClaim("realm_access")
+	if !ok {
+		return nil, false
+	}
+	return realmAccess.getStringSliceClaim("roles")
+}
+func (kt *defaultKeycloakAccessToken) SessionState() (string, bool) {
+	return kt.claims.GetClaimMustString("session_state")
+}
+
+func (kt *defaultKeycloakAccessToken) HasClientRole(clientID, role string) bool {
+	roles, _ := kt.ClientRoles(clientID)
+	return containsString(roles, role)
+}
+
+// HasGroup checks the group membership. Keycloak emits either group names or full group paths,
+// depending on the group mapper configuration, hence the leading slash is not significant.
+func (kt *defaultKeycloakAccessToken) HasGroup(group string) bool {
+	groups, _ := kt.Groups()
+	for _, item := range groups {
+		if strings.TrimPrefix(item, "/") == strings.TrimPrefix(group, "/") {
+			return true
+		}
+	}
+	return false
+}
+func (kt *defaultKeycloakAccessToken) HasRealmRole(role string) bool {
+	roles, _ := kt.RealmRoles()
+	return containsString(roles, role)
+}
+
+// DefaultKeycloakAccessToken returns a Keycloak view over the access token.
+func DefaultKeycloakAccessToken(accessToken AccessToken) KeycloakAccessToken {
+	return &defaultKeycloakAccessToken{AccessToken: accessToken, claims: accessToken.RawClaims()}
+}
+
+func containsString(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package tokens
+
+import "testing"
+
+func TestKeycloakAccessToken(t *testing.T) {
+	accessToken, parseErr := defaultInsecureAccessToken(t, tokenKeycloak)
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+	keycloakToken := DefaultKeycloakAccessToken(accessToken)
+
+	realmRoles, realmRolesOK := keycloakToken.RealmRoles()
+	if !realmRolesOK {
+		t.Fatal("Expected realm roles to be OK")
+	}
+	if len(realmRoles) != 2 {
+		t.Fatalf("Expected 2 realm roles but received '%v'", realmRoles)
+	}
+	if !keycloakToken.HasRealmRole("uma_authorization") {
+		t.Fatal("Expected token to have the uma_authorization realm role")
+	}
+	if keycloakToken.HasRealmRole("manage-account") {
+		t.Fatal("Expected token not to have the manage-account realm role")
+	}
+
+	clientRoles, clientRolesOK := keycloakToken.ClientRoles("account")
+	if !clientRolesOK {
+		t.Fatal("Expected account client roles to be OK")
+	}
+	if len(clientRoles) != 3 {
+		t.Fatalf("Expected 3 account client roles but received '%v'", clientRoles)
+	}
+	if !keycloakToken.HasClientRole("account", "view-profile") {
+		t.Fatal("Expected token to have the view-profile account client role")
+	}
+	if _, ok := keycloakToken.ClientRoles("non-existing"); ok {
+		t.Fatal("Expected non-existing client roles not to be OK")
+	}
+
+	allowedOrigins, allowedOriginsOK := keycloakToken.AllowedOrigins()
+	if !allowedOriginsOK {
+		t.Fatal("Expected allowed origins to be OK")
+	}
+	if len(allowedOrigins) != 1 || allowedOrigins[0] != "http://localhost:8081" {
+		t.Fatalf("Expected allowed origins to be '[http://localhost:8081]' but received '%v'", allowedOrigins)
+	}
+
+	if sessionState, _ := keycloakToken.SessionState(); sessionState != "3b65e46a-a6ad-4dff-8584-49b0e6a21a23" {
+		t.Fatalf("Expected session state different than received '%s'", sessionState)
+	}
+	if azp, _ := keycloakToken.Azp(); azp != "customers" {
+		t.Fatalf("Expected azp to be 'customers' but received '%s'", azp)
+	}
+	if acr, _ := keycloakToken.Acr(); acr != "1" {
+		t.Fatalf("Expected acr to be '1' but received '%s'", acr)
+	}
+	if sub, _ := keycloakToken.Sub(); sub != "5495c2ff-0335-4373-8b36-4cd943601e2c" {
+		t.Fatalf("Expected sub different than received '%s'", sub)
+	}
+}
+
+func TestKeycloakAccessTokenGroups(t *testing.T) {
+	keycloakToken := DefaultKeycloakAccessToken(DefaultAccessToken(Claims{
+		"groups": []interface{}{"/admins", "/customers/eu"},
+	}))
+	if !keycloakToken.HasGroup("admins") {
+		t.Fatal("Expected token to be a member of admins")
+	}
+	if !keycloakToken.HasGroup("/customers/eu") {
+		t.Fatal("Expected token to be a member of /customers/eu")
+	}
+	if keycloakToken.HasGroup("customers") {
+		t.Fatal("Expected token not to be a member of customers")
+	}
+	mixedToken := DefaultKeycloakAccessToken(DefaultAccessToken(Claims{
+		"groups": []interface{}{"/admins", 1},
+	}))
+	if groups, ok := mixedToken.Groups(); !ok || len(groups) != 1 || groups[0] != "/admins" {
+		t.Fatalf("Expected non-string groups to be skipped but received '%v'", groups)
+	}
+}
@@ -0,0 +1,230 @@
+package tokens
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidClaimPath indicates a claim path which can't be parsed.
+	ErrInvalidClaimPath = errInvalidClaimPath()
+)
+
+func errInvalidClaimPath() error { return errors.New("invalid claim path") }
+
+// ClaimPath represents a parsed path to a nested claim.
+type ClaimPath []string
+
+// ParseClaimPath parses a claim path.
+//
+// Paths starting with / are RFC 6901 JSON Pointers, for example: /resource_access/account/roles.
+// Other paths use the dot and bracket notation, for example: resource_access.account.roles,
+// realm_access.roles[0] or ["https://example.com/roles"]. Keys containing dots are either
+// quoted in brackets or escaped with a backslash: https://example\.com/roles.
+// Numeric segments index into arrays.
+func ParseClaimPath(path string) (ClaimPath, error) {
+	if path == "" {
+		return nil, ErrInvalidClaimPath
+	}
+	if strings.HasPrefix(path, "/") {
+		return parseJSONPointer(path), nil
+	}
+	return parseDotPath(path)
+}
+
+func parseJSONPointer(path string) ClaimPath {
+	segments := strings.Split(path[1:], "/")
+	for index, segment := range segments {
+		segments[index] = strings.Replace(strings.Replace(segment, "~1", "/", -1), "~0", "~", -1)
+	}
+	return ClaimPath(segments)
+}
+
+func parseDotPath(path string) (ClaimPath, error) {
+	segments := ClaimPath{}
+	current := strings.Builder{}
+	pending := false // is there a segment being built
+	afterBracket := false
+	for i := 0; i < len(path); i++ {
+		char := path[i]
+		switch {
+		case char == '\\':
+			if i+1 >= len(path) || afterBracket {
+				return nil, ErrInvalidClaimPath
+			}
+			i++
+			current.WriteByte(path[i])
+			pending = true
+		case char == '.':
+			if !pending && !afterBracket {
+				return nil, ErrInvalidClaimPath
+			}
+			if pending {
+				segments = append(segments, current.String())
+				current.Reset()
+			}
+			pending = false
+			afterBracket = false
+			if i == len(path)-1 {
+				return nil, ErrInvalidClaimPath
+			}
+		case char == '[':
+			if pending {
+				segments = append(segments, current.String())
+				current.Reset()
+				pending = false
+			}
+			segment, next, err := parseBracket(path, i)
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, segment)
+			i = next
+			afterBracket = true
+		default:
+			if afterBracket {
+				return nil, ErrInvalidClaimPath
+			}
+			current.WriteByte(char)
+			pending = true
+		}
+	}
+	if pending {
+		segments = append(segments, current.String())
+	}
+	return segments, nil
+}
+
+// parseBracket parses a bracket segment starting at the given position,
+// returns the segment and the position of the closing bracket.
+func parseBracket(path string, start int) (string, int, error) {
+	i := start + 1
+	if i < len(path) && (path[i] == '\'' || path[i] == '"') {
+		quote := path[i]
+		segment := strings.Builder{}
+		for i++; i < len(path); i++ {
+			switch path[i] {
+			case '\\':
+				if i+1 >= len(path) {
+					return "", 0, ErrInvalidClaimPath
+				}
+				i++
+				segment.WriteByte(path[i])
+			case quote:
+				if i+1 >= len(path) || path[i+1] != ']' {
+					return "", 0, ErrInvalidClaimPath
+				}
+				return segment.String(), i + 1, nil
+			default:
+				segment.WriteByte(path[i])
+			}
+		}
+		return "", 0, ErrInvalidClaimPath
+	}
+	end := strings.IndexByte(path[i:], ']')
+	if end <= 0 {
+		return "", 0, ErrInvalidClaimPath
+	}
+	return path[i : i+end], i + end, nil
+}
+
+// Lookup returns the value at the path.
+func (p ClaimPath) Lookup(claims Claims) (interface{}, bool) {
+	var current interface{} = claims
+	for _, segment := range p {
+		switch tvalue := current.(type) {
+		case Claims:
+			value, ok := tvalue[segment]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case map[string]interface{}:
+			value, ok := tvalue[segment]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(tvalue) {
+				return nil, false
+			}
+			current = tvalue[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// Query returns the value of a nested claim.
+// See ParseClaimPath for the path syntax.
+func (c Claims) Query(path string) (interface{}, error) {
+	claimPath, err := ParseClaimPath(path)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := claimPath.Lookup(c)
+	if !ok {
+		return nil, ErrClaimNotFound
+	}
+	return value, nil
+}
+
+// QueryString returns the value of a nested string claim.
+func (c Claims) QueryString(path string) (string, error) {
+	value, err := c.Query(path)
+	if err != nil {
+		return "", err
+	}
+	return convertString(path, value)
+}
+
+// QueryInt64 returns the value of a nested integer claim.
+func (c Claims) QueryInt64(path string) (int64, error) {
+	value, err := c.Query(path)
+	if err != nil {
+		return 0, err
+	}
+	return convertInt64(path, value)
+}
+
+// QueryBool returns the value of a nested boolean claim.
+func (c Claims) QueryBool(path string) (bool, error) {
+	value, err := c.Query(path)
+	if err != nil {
+		return false, err
+	}
+	return convertBool(path, value)
+}
+
+// QueryStringSlice returns the value of a nested string array claim.
+// A claim given as a single string is returned as a single item slice.
+func (c Claims) QueryStringSlice(path string) ([]string, error) {
+	value, err := c.Query(path)
+	if err != nil {
+		return nil, err
+	}
+	return convertStringSlice(path, value)
+}
+
+// QueryTime returns the value of a nested NumericDate claim as time.
+func (c Claims) QueryTime(path string) (time.Time, error) {
+	value, err := c.Query(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return convertTime(path, value)
+}
+
+// QueryClaims returns the value of a nested JSON object claim.
+func (c Claims) QueryClaims(path string) (Claims, error) {
+	value, err := c.Query(path)
+	if err != nil {
+		return nil, err
+	}
+	return convertClaims(path, value)
+}
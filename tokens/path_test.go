@@ -0,0 +1,69 @@
+package tokens
+
+import "testing"
+
+func TestParseClaimPath(t *testing.T) {
+	expectations := map[string][]string{
+		"realm_access.roles":                 {"realm_access", "roles"},
+		"resource_access['my.app'].roles[0]": {"resource_access", "my.app", "roles", "0"},
+		`["https://example.com/roles"]`:      {"https://example.com/roles"},
+		`https://example\.com/roles`:         {"https://example.com/roles"},
+		"/resource_access/account/roles":     {"resource_access", "account", "roles"},
+		"/a~1b/c~0d":                         {"a/b", "c~d"},
+		"a[0][1]":                            {"a", "0", "1"},
+	}
+	for path, expected := range expectations {
+		claimPath, err := ParseClaimPath(path)
+		if err != nil {
+			t.Fatalf("Expected path '%s' to parse but received '%v'", path, err)
+		}
+		if len(claimPath) != len(expected) {
+			t.Fatalf("Expected path '%s' to parse into '%v' but received '%v'", path, expected, claimPath)
+		}
+		for index := range expected {
+			if claimPath[index] != expected[index] {
+				t.Fatalf("Expected path '%s' to parse into '%v' but received '%v'", path, expected, claimPath)
+			}
+		}
+	}
+	for _, path := range []string{"", "a..b", ".a", "a.", "a[0", "a['b]", "a[0]b", `a\`} {
+		if _, err := ParseClaimPath(path); err != ErrInvalidClaimPath {
+			t.Fatalf("Expected path '%s' not to parse but received '%v'", path, err)
+		}
+	}
+}
+
+func TestClaimsQuery(t *testing.T) {
+	keycloakToken, _ := defaultInsecureAccessToken(t, tokenKeycloak)
+	claims := keycloakToken.RawClaims()
+
+	roles, err := claims.QueryStringSlice("resource_access.account.roles")
+	if err != nil || len(roles) != 3 {
+		t.Fatalf("Expected account roles but received '%v', '%v'", roles, err)
+	}
+	role, err := claims.QueryString("/realm_access/roles/1")
+	if err != nil || role != "uma_authorization" {
+		t.Fatalf("Expected realm role 'uma_authorization' but received '%v', '%v'", role, err)
+	}
+	if _, err := claims.QueryString("realm_access.roles[5]"); err != ErrClaimNotFound {
+		t.Fatalf("Expected claim not found error but received '%v'", err)
+	}
+	if _, err := claims.QueryInt64("realm_access.roles"); err == nil {
+		t.Fatal("Expected type mismatch error for an array read as int64")
+	}
+	if verified, err := claims.QueryBool("email_verified"); err != nil || !verified {
+		t.Fatalf("Expected email verified but received '%v', '%v'", verified, err)
+	}
+	if _, err := claims.QueryClaims("realm_access"); err != nil {
+		t.Fatalf("Expected realm access object but received '%v'", err)
+	}
+
+	namespaced := Claims{"https://example.com/roles": []interface{}{"admin"}}
+	if roles, err := namespaced.QueryStringSlice(`['https://example.com/roles']`); err != nil || len(roles) != 1 {
+		t.Fatalf("Expected namespaced roles but received '%v', '%v'", roles, err)
+	}
+	_, err = claims.QueryInt64("realm_access.roles")
+	if typeError, ok := err.(*ClaimTypeError); !ok || typeError.Claim != "realm_access.roles" {
+		t.Fatalf("Expected the type mismatch error to report the path but received '%v'", err)
+	}
+}
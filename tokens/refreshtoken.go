@@ -15,9 +15,11 @@ type RefreshToken interface {
 	Iss() (string, bool)
 	Jti() (string, bool)
 	Scope() (string, bool)
+	Scopes() (Scopes, bool)
 	Sub() (string, bool)
 	// Common convenience properties:
 	Aud() (interface{}, bool)
+	Audience() (Audience, bool)
 	Nbf() (int64, bool)
 	Typ() (string, bool)
 	// Other convenience methods:
@@ -32,6 +34,9 @@ type defaultRefreshToken struct {
 func (rt *defaultRefreshToken) Aud() (interface{}, bool) {
 	return rt.claims.GetClaim("aud")
 }
+func (rt *defaultRefreshToken) Audience() (Audience, bool) {
+	return rt.claims.getAudienceClaim()
+}
 func (rt *defaultRefreshToken) Azp() (string, bool) {
 	return rt.claims.GetClaimMustString("azp")
 }
@@ -51,14 +56,10 @@ func (rt *defaultRefreshToken) Nbf() (int64, bool) {
 	return rt.claims.getInt64Claim("nbf")
 }
 func (rt *defaultRefreshToken) Scope() (string, bool) {
-	// ORY Hydra returns this claim as scp.
-	// Keycloak returns this as scope (as specified in the spec).
-	for _, claimName := range []string{"scp", "scope"} {
-		if rt.claims.HasClaim(claimName) {
-			return rt.claims.GetClaimMustString(claimName)
-		}
-	}
-	return "", false
+	return rt.claims.getScopeClaim()
+}
+func (rt *defaultRefreshToken) Scopes() (Scopes, bool) {
+	return rt.claims.getScopesClaim()
 }
 func (rt *defaultRefreshToken) Sub() (string, bool) {
 	return rt.claims.GetClaimMustString("sub")
@@ -70,7 +71,7 @@ func (rt *defaultRefreshToken) Typ() (string, bool) {
 // DefaultRefreshToken returns an instacne of the refresh token.
 // Call this function using claims returned from jwks.Validator.ValidateToken(string).
 func DefaultRefreshToken(claims Claims) RefreshToken {
-	return &defaultRefreshToken{claims: claims}
+	return &defaultRefreshToken{defaultToken: defaultToken{claims: claims}, claims: claims}
 }
 
 func defaultInsecureRefreshToken(t *testing.T, rawToken string) (RefreshToken, error) {
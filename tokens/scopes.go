@@ -0,0 +1,164 @@
+package tokens
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Scopes represents a set of OAuth 2.0 scopes.
+// Scopes created with NewScopes or ParseScopes are deduplicated and sorted.
+type Scopes []string
+
+var scopesType = reflect.TypeOf(Scopes{})
+
+// NewScopes returns a set of scopes from the given values.
+func NewScopes(values ...string) Scopes {
+	seen := map[string]bool{}
+	scopes := Scopes{}
+	for _, value := range values {
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		scopes = append(scopes, value)
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+// ParseScopes parses scopes given either as an RFC 6749 space-delimited string
+// or as a JSON array.
+func ParseScopes(value interface{}) (Scopes, bool) {
+	switch tvalue := value.(type) {
+	case string:
+		return NewScopes(strings.Fields(tvalue)...), true
+	case Scopes:
+		return NewScopes(tvalue...), true
+	case []string:
+		return NewScopes(tvalue...), true
+	case []interface{}:
+		values := []string{}
+		for _, item := range tvalue {
+			stringItem, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			values = append(values, stringItem)
+		}
+		return NewScopes(values...), true
+	default:
+		return nil, false
+	}
+}
+
+// Has checks if the set contains the scope.
+func (s Scopes) Has(scope string) bool {
+	for _, item := range s {
+		if item == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAll checks if the set contains all of the scopes.
+func (s Scopes) HasAll(scopes ...string) bool {
+	for _, scope := range scopes {
+		if !s.Has(scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasAny checks if the set contains any of the scopes.
+func (s Scopes) HasAny(scopes ...string) bool {
+	for _, scope := range scopes {
+		if s.Has(scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match checks if any scope in the set matches the pattern.
+// A pattern ending with * matches by prefix, read:* matches read:users.
+func (s Scopes) Match(pattern string) bool {
+	for _, item := range s {
+		if matchScope(pattern, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// Grants checks if the set satisfies all of the required scopes.
+// A required scope may be a pattern, read:* is satisfied by read:users. Scopes of the set
+// are always matched literally, a set containing * or read:* grants only these exact scopes.
+func (s Scopes) Grants(required ...string) bool {
+	for _, pattern := range required {
+		if !s.Match(pattern) {
+			return false
+		}
+	}
+	return true
+}
+
+// Union returns scopes present in either of the sets.
+func (s Scopes) Union(other Scopes) Scopes {
+	return NewScopes(append(append([]string{}, s...), other...)...)
+}
+
+// Intersect returns scopes present in both sets.
+func (s Scopes) Intersect(other Scopes) Scopes {
+	values := []string{}
+	for _, item := range s {
+		if other.Has(item) {
+			values = append(values, item)
+		}
+	}
+	return NewScopes(values...)
+}
+
+// Difference returns scopes present in this set but not in the other set.
+func (s Scopes) Difference(other Scopes) Scopes {
+	values := []string{}
+	for _, item := range s {
+		if !other.Has(item) {
+			values = append(values, item)
+		}
+	}
+	return NewScopes(values...)
+}
+
+// String returns the sorted RFC 6749 space-delimited representation.
+func (s Scopes) String() string {
+	return strings.Join(NewScopes(s...), " ")
+}
+
+// UnmarshalJSON accepts both, a space-delimited string and an array.
+func (s *Scopes) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if value == nil {
+		*s = nil
+		return nil
+	}
+	scopes, ok := ParseScopes(value)
+	if !ok {
+		return &json.UnmarshalTypeError{Value: string(data), Type: scopesType}
+	}
+	*s = scopes
+	return nil
+}
+
+func matchScope(pattern, scope string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(scope, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == scope
+}
@@ -0,0 +1,92 @@
+package tokens
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseScopes(t *testing.T) {
+	fromString, ok := ParseScopes("profile openid  email openid")
+	if !ok {
+		t.Fatal("Expected space-delimited scopes to parse")
+	}
+	if fromString.String() != "email openid profile" {
+		t.Fatalf("Expected scopes to be 'email openid profile' but received '%s'", fromString.String())
+	}
+	fromArray, ok := ParseScopes([]interface{}{"openid", "email", "profile"})
+	if !ok {
+		t.Fatal("Expected array scopes to parse")
+	}
+	if fromArray.String() != fromString.String() {
+		t.Fatalf("Expected scopes parsed from an array to equal '%s' but received '%s'", fromString, fromArray)
+	}
+	if _, ok := ParseScopes([]interface{}{"openid", 1}); ok {
+		t.Fatal("Expected array with non-string values not to parse")
+	}
+	if _, ok := ParseScopes(1); ok {
+		t.Fatal("Expected a number not to parse")
+	}
+}
+
+func TestScopesChecks(t *testing.T) {
+	scopes := NewScopes("openid", "read:users", "write:*")
+	if !scopes.Has("openid") || scopes.Has("read") {
+		t.Fatal("Expected Has to match exact scopes only")
+	}
+	if !scopes.HasAll("openid", "read:users") || scopes.HasAll("openid", "offline") {
+		t.Fatal("Expected HasAll to require all scopes")
+	}
+	if !scopes.HasAny("offline", "openid") || scopes.HasAny("offline") {
+		t.Fatal("Expected HasAny to require any scope")
+	}
+	if !scopes.Match("read:*") || scopes.Match("delete:*") {
+		t.Fatal("Expected Match to match by prefix")
+	}
+	if !scopes.Grants("openid", "read:*") || scopes.Grants("openid", "delete:*") {
+		t.Fatal("Expected Grants to match required patterns")
+	}
+	if scopes.Grants("write:users") || NewScopes("*").Grants("read:users") {
+		t.Fatal("Expected Grants to match scopes of the set literally")
+	}
+}
+
+func TestScopesSetOperations(t *testing.T) {
+	a := NewScopes("openid", "email")
+	b := NewScopes("email", "profile")
+	if a.Union(b).String() != "email openid profile" {
+		t.Fatalf("Expected union different than received '%s'", a.Union(b))
+	}
+	if a.Intersect(b).String() != "email" {
+		t.Fatalf("Expected intersection different than received '%s'", a.Intersect(b))
+	}
+	if a.Difference(b).String() != "openid" {
+		t.Fatalf("Expected difference different than received '%s'", a.Difference(b))
+	}
+}
+
+func TestScopesUnmarshalJSON(t *testing.T) {
+	target := struct {
+		FromString Scopes `json:"from_string"`
+		FromArray  Scopes `json:"from_array"`
+	}{}
+	if err := json.Unmarshal([]byte(`{"from_string":"openid email","from_array":["openid","email"]}`), &target); err != nil {
+		t.Fatalf("Expected scopes to unmarshal but received '%v'", err)
+	}
+	if !target.FromString.HasAll("openid", "email") || !target.FromArray.HasAll("openid", "email") {
+		t.Fatalf("Expected both scope forms to unmarshal but received '%v' and '%v'", target.FromString, target.FromArray)
+	}
+	if err := json.Unmarshal([]byte(`{"from_string":1}`), &target); err == nil {
+		t.Fatal("Expected a number not to unmarshal")
+	}
+}
+
+func TestTokenScopes(t *testing.T) {
+	hydraToken, _ := defaultInsecureAccessToken(t, tokenHydra)
+	keycloakToken, _ := defaultInsecureAccessToken(t, tokenKeycloak)
+	if scopes, ok := hydraToken.Scopes(); !ok || !scopes.HasAll("openid", "offline") {
+		t.Fatalf("Expected Hydra scopes '[offline openid]' but received '%v'", scopes)
+	}
+	if scopes, ok := keycloakToken.Scopes(); !ok || !scopes.HasAll("profile", "email") {
+		t.Fatalf("Expected Keycloak scopes '[email profile]' but received '%v'", scopes)
+	}
+}
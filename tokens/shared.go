@@ -1,58 +1,23 @@
 package tokens
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // Claims represents token claims.
 type Claims map[string]interface{}
 
 func (c Claims) getFloat64Claim(claim string) (float64, bool) {
 	if value, ok := c[claim]; ok {
-		switch tvalue := value.(type) {
-		case float32:
-			return float64(tvalue), true
-		case uint:
-			return float64(tvalue), true
-		case uint32:
-			return float64(tvalue), true
-		case uint64:
-			return float64(tvalue), true
-		case int:
-			return float64(tvalue), true
-		case int32:
-			return float64(tvalue), true
-		case int64:
-			return float64(tvalue), true
-		case float64:
-			return tvalue, true
-		default:
-			return 0, false
-		}
+		return toFloat64(value)
 	}
 	return 0, false
 }
 
 func (c Claims) getInt64Claim(claim string) (int64, bool) {
 	if value, ok := c[claim]; ok {
-		switch tvalue := value.(type) {
-		case float32:
-			return int64(tvalue), true
-		case float64:
-			return int64(tvalue), true
-		case uint:
-			return int64(tvalue), true
-		case uint32:
-			return int64(tvalue), true
-		case uint64:
-			return int64(tvalue), true
-		case int:
-			return int64(tvalue), true
-		case int32:
-			return int64(tvalue), true
-		case int64:
-			return tvalue, true
-		default:
-			return 0, false
-		}
+		return toInt64(value)
 	}
 	return 0, false
 }
@@ -69,6 +34,60 @@ func (c Claims) getBoolClaim(claim string) (bool, bool) {
 	return false, false
 }
 
+// getStringSliceClaim skips the non-string items of an array claim,
+// GetStringSlice rejects them.
+func (c Claims) getStringSliceClaim(claim string) ([]string, bool) {
+	if value, ok := c[claim]; ok {
+		switch tvalue := value.(type) {
+		case []string:
+			return tvalue, true
+		case []interface{}:
+			result := []string{}
+			for _, item := range tvalue {
+				if stringItem, ok := item.(string); ok {
+					result = append(result, stringItem)
+				}
+			}
+			return result, true
+		default:
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+func (c Claims) getClaimsClaim(claim string) (Claims, bool) {
+	if value, ok := c[claim]; ok {
+		return toClaims(value)
+	}
+	return nil, false
+}
+
+// getScopeClaim returns the scope as an RFC 6749 space-delimited string.
+// ORY Hydra returns this claim as a scp array.
+// Keycloak returns this as scope (as specified in the spec).
+func (c Claims) getScopeClaim() (string, bool) {
+	for _, claimName := range []string{"scp", "scope"} {
+		if !c.HasClaim(claimName) {
+			continue
+		}
+		if values, ok := c.getStringSliceClaim(claimName); ok {
+			return strings.Join(values, " "), true
+		}
+		return c.GetClaimMustString(claimName)
+	}
+	return "", false
+}
+
+func (c Claims) getScopesClaim() (Scopes, bool) {
+	for _, claimName := range []string{"scp", "scope"} {
+		if value, ok := c[claimName]; ok {
+			return ParseScopes(value)
+		}
+	}
+	return nil, false
+}
+
 func (c Claims) HasClaim(claim string) bool {
 	_, ok := c[claim]
 	return ok
@@ -101,6 +120,82 @@ const (
 	RefreshTokenType TokenType = "Refresh"
 )
 
+func toFloat64(value interface{}) (float64, bool) {
+	switch tvalue := value.(type) {
+	case float32:
+		return float64(tvalue), true
+	case uint:
+		return float64(tvalue), true
+	case uint32:
+		return float64(tvalue), true
+	case uint64:
+		return float64(tvalue), true
+	case int:
+		return float64(tvalue), true
+	case int32:
+		return float64(tvalue), true
+	case int64:
+		return float64(tvalue), true
+	case float64:
+		return tvalue, true
+	default:
+		return 0, false
+	}
+}
+
+func toInt64(value interface{}) (int64, bool) {
+	switch tvalue := value.(type) {
+	case float32:
+		return int64(tvalue), true
+	case float64:
+		return int64(tvalue), true
+	case uint:
+		return int64(tvalue), true
+	case uint32:
+		return int64(tvalue), true
+	case uint64:
+		return int64(tvalue), true
+	case int:
+		return int64(tvalue), true
+	case int32:
+		return int64(tvalue), true
+	case int64:
+		return tvalue, true
+	default:
+		return 0, false
+	}
+}
+
+func toStringSlice(value interface{}) ([]string, bool) {
+	switch tvalue := value.(type) {
+	case []string:
+		return tvalue, true
+	case []interface{}:
+		result := []string{}
+		for _, item := range tvalue {
+			stringItem, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			result = append(result, stringItem)
+		}
+		return result, true
+	default:
+		return nil, false
+	}
+}
+
+func toClaims(value interface{}) (Claims, bool) {
+	switch tvalue := value.(type) {
+	case Claims:
+		return tvalue, true
+	case map[string]interface{}:
+		return Claims(tvalue), true
+	default:
+		return nil, false
+	}
+}
+
 func getTokenParts(parts []string) (string, string, string) {
 	return parts[0], parts[1], parts[2]
 }
@@ -0,0 +1,55 @@
+package tokens
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TokenSource supplies tokens for outgoing requests.
+type TokenSource interface {
+	Token(ctx context.Context) (JWT, error)
+}
+
+// TokenSourceFunc adapts a function to the TokenSource interface.
+type TokenSourceFunc func(ctx context.Context) (JWT, error)
+
+// Token calls the function.
+func (f TokenSourceFunc) Token(ctx context.Context) (JWT, error) {
+	return f(ctx)
+}
+
+type reuseTokenSource struct {
+	sync.Mutex
+	expiresAt time.Time
+	leeway    time.Duration
+	now       func() time.Time
+	source    TokenSource
+	token     JWT
+}
+
+// ReuseTokenSource returns a token source reusing the token obtained from the source
+// until the leeway before the token expires, the token is then refreshed through the source.
+// Tokens without expires_in are reused indefinitely.
+func ReuseTokenSource(source TokenSource, leeway time.Duration) TokenSource {
+	return &reuseTokenSource{leeway: leeway, now: time.Now, source: source}
+}
+
+func (s *reuseTokenSource) Token(ctx context.Context) (JWT, error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.token != nil && (s.expiresAt.IsZero() || s.now().Before(s.expiresAt.Add(-s.leeway))) {
+		return s.token, nil
+	}
+	obtainedAt := s.now()
+	token, err := s.source.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.token = token
+	s.expiresAt = time.Time{}
+	if token.ExpiresIn() > 0 {
+		s.expiresAt = obtainedAt.Add(time.Duration(token.ExpiresIn()) * time.Second)
+	}
+	return token, nil
+}
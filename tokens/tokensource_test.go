@@ -0,0 +1,39 @@
+package tokens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestReuseTokenSource(t *testing.T) {
+	calls := 0
+	source := ReuseTokenSource(TokenSourceFunc(func(ctx context.Context) (JWT, error) {
+		calls++
+		if calls == 3 {
+			return nil, errors.New("token endpoint unavailable")
+		}
+		return DefaultJWT([]byte(fmt.Sprintf(`{"access_token":"token-%d","expires_in":60}`, calls)))
+	}), 10*time.Second)
+	now := time.Now()
+	source.(*reuseTokenSource).now = func() time.Time { return now }
+
+	token, err := source.Token(context.Background())
+	if err != nil || token.AccessToken() != "token-1" {
+		t.Fatalf("Expected the first token but received '%v', '%v'", token, err)
+	}
+	now = now.Add(49 * time.Second)
+	if token, _ := source.Token(context.Background()); token.AccessToken() != "token-1" {
+		t.Fatalf("Expected the token to be reused but received '%s'", token.AccessToken())
+	}
+	now = now.Add(time.Second)
+	if token, _ := source.Token(context.Background()); token.AccessToken() != "token-2" {
+		t.Fatalf("Expected the token to be refreshed within the leeway but received '%s'", token.AccessToken())
+	}
+	now = now.Add(time.Minute)
+	if _, err := source.Token(context.Background()); err == nil {
+		t.Fatal("Expected the source error to be returned")
+	}
+}
@@ -0,0 +1,102 @@
+package tokens
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrAudienceMismatch indicates a token without any of the expected audiences.
+	ErrAudienceMismatch = errAudienceMismatch()
+	// ErrExpirationMissing indicates a token without the exp claim.
+	ErrExpirationMissing = errExpirationMissing()
+	// ErrIssuedInFuture indicates a token with the iat claim in the future.
+	ErrIssuedInFuture = errIssuedInFuture()
+	// ErrIssuerMismatch indicates a token issued by an unexpected issuer.
+	ErrIssuerMismatch = errIssuerMismatch()
+	// ErrResourceNotInAudience indicates a token without a requested RFC 8707 resource in its audience.
+	ErrResourceNotInAudience = errResourceNotInAudience()
+	// ErrTokenExpired indicates an expired token.
+	ErrTokenExpired = errTokenExpired()
+	// ErrTokenNotValidYet indicates a token used before its nbf claim.
+	ErrTokenNotValidYet = errTokenNotValidYet()
+)
+
+func errAudienceMismatch() error      { return errors.New("token audience mismatch") }
+func errExpirationMissing() error     { return errors.New("token has no expiration") }
+func errIssuedInFuture() error        { return errors.New("token issued in the future") }
+func errIssuerMismatch() error        { return errors.New("token issuer mismatch") }
+func errResourceNotInAudience() error { return errors.New("resource not in token audience") }
+func errTokenExpired() error          { return errors.New("token expired") }
+func errTokenNotValidYet() error      { return errors.New("token not valid yet") }
+
+// Expectations represents the values the token claims are validated against.
+// Empty values are not validated.
+type Expectations struct {
+	// Audiences: the token audience must contain at least one of these.
+	Audiences []string
+	// Issuer: the token must be issued by this issuer.
+	Issuer string
+	// Leeway: the clock skew tolerance for the exp, nbf and iat claims.
+	Leeway time.Duration
+	// Now: returns the current time, time.Now is used if not set.
+	Now func() time.Time
+	// Resources: RFC 8707 resource indicators, the token audience must contain all of these.
+	Resources []string
+}
+
+// ClaimsValidator validates token claims.
+type ClaimsValidator interface {
+	Validate(claims Claims) error
+}
+
+type defaultClaimsValidator struct {
+	expectations *Expectations
+}
+
+// DefaultClaimsValidator returns a claims validator for the expectations.
+// The token must carry the exp claim, the nbf and iat claims are validated when present.
+func DefaultClaimsValidator(expectations *Expectations) ClaimsValidator {
+	if expectations == nil {
+		expectations = &Expectations{}
+	}
+	return &defaultClaimsValidator{expectations: expectations}
+}
+
+func (v *defaultClaimsValidator) Validate(claims Claims) error {
+	now := time.Now()
+	if v.expectations.Now != nil {
+		now = v.expectations.Now()
+	}
+	leeway := v.expectations.Leeway
+
+	exp, ok := claims.getInt64Claim("exp")
+	if !ok {
+		return ErrExpirationMissing
+	}
+	// RFC 7519 section 4.1.4: the token must not be accepted on or after the expiration time:
+	if !now.Add(-leeway).Before(time.Unix(exp, 0)) {
+		return ErrTokenExpired
+	}
+	if nbf, ok := claims.getInt64Claim("nbf"); ok && now.Add(leeway).Before(time.Unix(nbf, 0)) {
+		return ErrTokenNotValidYet
+	}
+	if iat, ok := claims.getInt64Claim("iat"); ok && now.Add(leeway).Before(time.Unix(iat, 0)) {
+		return ErrIssuedInFuture
+	}
+
+	if v.expectations.Issuer != "" {
+		if iss, _ := claims.GetClaim("iss"); iss != v.expectations.Issuer {
+			return ErrIssuerMismatch
+		}
+	}
+
+	audience, _ := claims.getAudienceClaim()
+	if len(v.expectations.Audiences) > 0 && !audience.ContainsAny(v.expectations.Audiences...) {
+		return ErrAudienceMismatch
+	}
+	if len(v.expectations.Resources) > 0 && !audience.ContainsAll(v.expectations.Resources...) {
+		return ErrResourceNotInAudience
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package tokens
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAudience(t *testing.T) {
+	hydraToken, _ := defaultInsecureAccessToken(t, tokenHydra)
+	keycloakToken, _ := defaultInsecureAccessToken(t, tokenKeycloak)
+	if audience, ok := hydraToken.Audience(); !ok || len(audience) != 0 {
+		t.Fatalf("Expected Hydra audience to be empty but received '%v'", audience)
+	}
+	if audience, ok := keycloakToken.Audience(); !ok || !audience.Contains("account") {
+		t.Fatalf("Expected Keycloak audience to be '[account]' but received '%v'", audience)
+	}
+	if _, ok := ParseAudience([]interface{}{"api", 1}); ok {
+		t.Fatal("Expected audience with non-string values not to parse")
+	}
+}
+
+func TestClaimsValidator(t *testing.T) {
+	keycloakToken, _ := defaultInsecureAccessToken(t, tokenKeycloak)
+	claims := keycloakToken.RawClaims()
+	issuedAt := time.Unix(1618150928, 0)
+	validAt := func() time.Time { return issuedAt.Add(time.Minute) }
+
+	if err := DefaultClaimsValidator(&Expectations{
+		Audiences: []string{"other", "account"},
+		Issuer:    "http://127.0.0.1:8081/auth/realms/multi-customer",
+		Now:       validAt,
+		Resources: []string{"account"},
+	}).Validate(claims); err != nil {
+		t.Fatalf("Expected claims to validate but received '%v'", err)
+	}
+
+	if err := DefaultClaimsValidator(nil).Validate(claims); err != ErrTokenExpired {
+		t.Fatalf("Expected token expired error but received '%v'", err)
+	}
+	if err := DefaultClaimsValidator(&Expectations{
+		Now: func() time.Time { return issuedAt.Add(-time.Minute) },
+	}).Validate(claims); err != ErrIssuedInFuture {
+		t.Fatalf("Expected issued in future error but received '%v'", err)
+	}
+	if err := DefaultClaimsValidator(&Expectations{
+		Leeway: 2 * time.Minute,
+		Now:    func() time.Time { return issuedAt.Add(-time.Minute) },
+	}).Validate(claims); err != nil {
+		t.Fatalf("Expected leeway to be honoured but received '%v'", err)
+	}
+	if err := DefaultClaimsValidator(&Expectations{
+		Issuer: "http://127.0.0.1:4444/",
+		Now:    validAt,
+	}).Validate(claims); err != ErrIssuerMismatch {
+		t.Fatalf("Expected issuer mismatch error but received '%v'", err)
+	}
+	if err := DefaultClaimsValidator(&Expectations{
+		Audiences: []string{"api"},
+		Now:       validAt,
+	}).Validate(claims); err != ErrAudienceMismatch {
+		t.Fatalf("Expected audience mismatch error but received '%v'", err)
+	}
+	if err := DefaultClaimsValidator(&Expectations{
+		Resources: []string{"account", "https://api.example.com"},
+		Now:       validAt,
+	}).Validate(claims); err != ErrResourceNotInAudience {
+		t.Fatalf("Expected resource not in audience error but received '%v'", err)
+	}
+	expiresAt := time.Unix(1618150928, 0)
+	for name, testCase := range map[string]struct {
+		now      time.Time
+		leeway   time.Duration
+		expected error
+	}{
+		"before exp":         {now: expiresAt.Add(-time.Second), expected: nil},
+		"at exp":             {now: expiresAt, expected: ErrTokenExpired},
+		"at exp with leeway": {now: expiresAt.Add(time.Minute - time.Second), leeway: time.Minute, expected: nil},
+		"at exp plus leeway": {now: expiresAt.Add(time.Minute), leeway: time.Minute, expected: ErrTokenExpired},
+	} {
+		now := testCase.now
+		if err := DefaultClaimsValidator(&Expectations{
+			Leeway: testCase.leeway,
+			Now:    func() time.Time { return now },
+		}).Validate(Claims{"exp": float64(expiresAt.Unix())}); err != testCase.expected {
+			t.Fatalf("Expected '%v' %s but received '%v'", testCase.expected, name, err)
+		}
+	}
+	if err := DefaultClaimsValidator(nil).Validate(Claims{}); err != ErrExpirationMissing {
+		t.Fatalf("Expected expiration missing error but received '%v'", err)
+	}
+	if err := DefaultClaimsValidator(&Expectations{Now: validAt}).Validate(Claims{
+		"exp": float64(issuedAt.Add(time.Hour).Unix()),
+		"nbf": float64(issuedAt.Add(time.Hour).Unix()),
+	}); err != ErrTokenNotValidYet {
+		t.Fatalf("Expected token not valid yet error but received '%v'", err)
+	}
+}
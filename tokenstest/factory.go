@@ -0,0 +1,142 @@
+package tokenstest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/radekg/app-kit-tokens/jwks"
+	"github.com/radekg/app-kit-tokens/signer"
+	"github.com/radekg/app-kit-tokens/tokens"
+	"gopkg.in/square/go-jose.v2"
+)
+
+// Option modifies the claims of a token issued at now.
+type Option func(claims tokens.Claims, now time.Time)
+
+// WithClaim sets a claim.
+func WithClaim(name string, value interface{}) Option {
+	return func(claims tokens.Claims, now time.Time) {
+		claims[name] = value
+	}
+}
+
+// WithClaims sets the claims.
+func WithClaims(values tokens.Claims) Option {
+	return func(claims tokens.Claims, now time.Time) {
+		for name, value := range values {
+			claims[name] = value
+		}
+	}
+}
+
+// WithoutClaim removes a claim.
+func WithoutClaim(name string) Option {
+	return func(claims tokens.Claims, now time.Time) {
+		delete(claims, name)
+	}
+}
+
+// ExpiresIn sets exp relative to the clock, a negative duration produces an expired token.
+func ExpiresIn(expiresIn time.Duration) Option {
+	return WithTime("exp", expiresIn)
+}
+
+// WithTime sets a NumericDate claim relative to the clock.
+func WithTime(name string, offset time.Duration) Option {
+	return func(claims tokens.Claims, now time.Time) {
+		claims[name] = now.Add(offset).Unix()
+	}
+}
+
+// Config represents the factory configuration.
+type Config struct {
+	// Algorithm: the signing algorithm, defaults to RS256.
+	Algorithm jose.SignatureAlgorithm
+	// Now: the clock, defaults to time.Now.
+	Now func() time.Time
+}
+
+// Factory signs tokens and provides the matching JWKS.
+type Factory interface {
+	// Claims returns the claims of the preset modified by the options.
+	Claims(preset Preset, options ...Option) tokens.Claims
+	// JWKS returns the key set verifying the tokens of the factory.
+	JWKS() jwks.JWKS
+	// KeySet returns the public keys of the factory.
+	KeySet() jose.JSONWebKeySet
+	// MustSign signs the token or fails the test.
+	MustSign(t testing.TB, preset Preset, options ...Option) string
+	// Sign signs the claims of the preset modified by the options.
+	Sign(preset Preset, options ...Option) (string, error)
+}
+
+type defaultFactory struct {
+	config  *Config
+	keyRing signer.KeyRing
+}
+
+// New returns a factory with a freshly generated signing key.
+func New(config *Config) (Factory, error) {
+	if config == nil {
+		config = &Config{}
+	}
+	algorithm := config.Algorithm
+	if algorithm == "" {
+		algorithm = jose.RS256
+	}
+	key, err := signer.GenerateKey(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	keyRing, err := signer.NewKeyRing(key.KeyID, key)
+	if err != nil {
+		return nil, err
+	}
+	return &defaultFactory{config: config, keyRing: keyRing}, nil
+}
+
+// MustNew returns a factory or fails the test.
+func MustNew(t testing.TB, config *Config) Factory {
+	factory, err := New(config)
+	if err != nil {
+		t.Fatalf("expected token factory but received '%v'", err)
+	}
+	return factory
+}
+
+func (f *defaultFactory) Claims(preset Preset, options ...Option) tokens.Claims {
+	now := f.now()
+	claims := preset(now)
+	for _, option := range options {
+		option(claims, now)
+	}
+	return claims
+}
+
+func (f *defaultFactory) JWKS() jwks.JWKS {
+	return jwks.FromKeySet(f.KeySet())
+}
+
+func (f *defaultFactory) KeySet() jose.JSONWebKeySet {
+	return f.keyRing.VerificationKeys()
+}
+
+func (f *defaultFactory) MustSign(t testing.TB, preset Preset, options ...Option) string {
+	rawToken, err := f.Sign(preset, options...)
+	if err != nil {
+		t.Fatalf("expected signed token but received '%v'", err)
+	}
+	return rawToken
+}
+
+func (f *defaultFactory) Sign(preset Preset, options ...Option) (string, error) {
+	// presets set every time claim, the signer defaults only apply to removed claims:
+	return signer.New(f.keyRing, &signer.Config{Now: f.now}).Sign(f.Claims(preset, options...), nil)
+}
+
+func (f *defaultFactory) now() time.Time {
+	if f.config.Now != nil {
+		return f.config.Now()
+	}
+	return time.Now()
+}
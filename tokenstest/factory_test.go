@@ -0,0 +1,74 @@
+package tokenstest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/radekg/app-kit-tokens/tokens"
+)
+
+func TestKeycloakPreset(t *testing.T) {
+	now := time.Unix(1618150928, 0)
+	clock := func() time.Time { return now }
+	factory := MustNew(t, &Config{Now: clock})
+
+	rawToken := factory.MustSign(t, KeycloakAccessToken, WithClaim("realm_access", map[string]interface{}{
+		"roles": []interface{}{"admin"},
+	}))
+	read := factory.JWKS().ReadSigned(rawToken)
+	if read.Error() != nil {
+		t.Fatalf("expected token to verify but received '%v'", read.Error())
+	}
+	keycloak := tokens.DefaultKeycloakAccessToken(tokens.DefaultAccessToken(read.Claims()))
+	if !keycloak.HasRealmRole("admin") || keycloak.HasRealmRole("offline_access") {
+		t.Fatal("expected the overridden realm roles")
+	}
+	if !keycloak.HasClientRole("account", "view-profile") {
+		t.Fatal("expected the preset client roles")
+	}
+	if sessionState, _ := keycloak.SessionState(); sessionState != KeycloakSessionState {
+		t.Fatalf("expected the preset session state but received '%s'", sessionState)
+	}
+	if exp, _ := keycloak.Exp(); exp != now.Add(5*time.Minute).Unix() {
+		t.Fatalf("expected exp relative to the clock but received '%d'", exp)
+	}
+
+	validator := tokens.DefaultClaimsValidator(&tokens.Expectations{Issuer: KeycloakIssuer, Now: clock})
+	if err := validator.Validate(read.Claims()); err != nil {
+		t.Fatalf("expected valid claims but received '%v'", err)
+	}
+	expired := factory.JWKS().ReadSigned(factory.MustSign(t, KeycloakAccessToken, ExpiresIn(-time.Second)))
+	if err := validator.Validate(expired.Claims()); err != tokens.ErrTokenExpired {
+		t.Fatalf("expected ErrTokenExpired but received '%v'", err)
+	}
+
+	refresh := tokens.DefaultRefreshToken(factory.Claims(KeycloakRefreshToken))
+	if typ, _ := refresh.Typ(); typ != "Refresh" {
+		t.Fatalf("expected the Refresh typ claim but received '%s'", typ)
+	}
+	if _, ok := factory.Claims(KeycloakIDToken, WithoutClaim("email"))["email"]; ok {
+		t.Fatal("expected the email claim to be removed")
+	}
+}
+
+func TestHydraPreset(t *testing.T) {
+	factory := MustNew(t, nil)
+	rawToken := factory.MustSign(t, HydraAccessToken, WithClaims(tokens.Claims{
+		"scp": []interface{}{"orders:read"},
+		"ext": map[string]interface{}{"tenant": "acme"},
+	}))
+	read := factory.JWKS().ReadSigned(rawToken)
+	if read.Error() != nil {
+		t.Fatalf("expected token to verify but received '%v'", read.Error())
+	}
+	hydra := tokens.DefaultHydraAccessToken(tokens.DefaultAccessToken(read.Claims()))
+	if scp, _ := hydra.Scp(); len(scp) != 1 || scp[0] != "orders:read" {
+		t.Fatalf("expected the overridden scp claim but received '%v'", scp)
+	}
+	if ext, _ := hydra.Ext(); ext["tenant"] != "acme" {
+		t.Fatalf("expected the ext claim but received '%v'", ext)
+	}
+	if clientID, _ := hydra.ClientID(); clientID != HydraClientID {
+		t.Fatalf("expected the preset client ID but received '%s'", clientID)
+	}
+}
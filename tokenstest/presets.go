@@ -0,0 +1,102 @@
+package tokenstest
+
+import (
+	"time"
+
+	"github.com/radekg/app-kit-tokens/tokens"
+)
+
+// Default preset values.
+const (
+	HydraClientID        = "my-client"
+	HydraIssuer          = "http://127.0.0.1:4444/"
+	KeycloakAzp          = "customers"
+	KeycloakIssuer       = "http://127.0.0.1:8081/auth/realms/multi-customer"
+	KeycloakSessionState = "3b65e46a-a6ad-4dff-8584-49b0e6a21a23"
+	KeycloakSubject      = "5495c2ff-0335-4373-8b36-4cd943601e2c"
+	KeycloakUsername     = "member@service-team"
+)
+
+// Preset returns the claims of a token issued at now.
+type Preset func(now time.Time) tokens.Claims
+
+// KeycloakAccessToken reproduces the shape of a Keycloak access token, valid for five minutes.
+func KeycloakAccessToken(now time.Time) tokens.Claims {
+	return tokens.Claims{
+		"acr":                "1",
+		"allowed-origins":    []interface{}{"http://localhost:8081"},
+		"aud":                "account",
+		"azp":                KeycloakAzp,
+		"email":              KeycloakUsername,
+		"email_verified":     true,
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"iat":                now.Unix(),
+		"iss":                KeycloakIssuer,
+		"jti":                "dddd2c65-4b5c-467a-9f5c-e3ae173b9426",
+		"preferred_username": KeycloakUsername,
+		"realm_access": map[string]interface{}{
+			"roles": []interface{}{"offline_access", "uma_authorization"},
+		},
+		"resource_access": map[string]interface{}{
+			"account": map[string]interface{}{
+				"roles": []interface{}{"manage-account", "manage-account-links", "view-profile"},
+			},
+		},
+		"scope":         "profile email",
+		"session_state": KeycloakSessionState,
+		"sub":           KeycloakSubject,
+		"typ":           "Bearer",
+	}
+}
+
+// KeycloakIDToken reproduces the shape of a Keycloak ID token, valid for five minutes.
+func KeycloakIDToken(now time.Time) tokens.Claims {
+	return tokens.Claims{
+		"acr":                "1",
+		"aud":                KeycloakAzp,
+		"auth_time":          now.Unix(),
+		"azp":                KeycloakAzp,
+		"email":              KeycloakUsername,
+		"email_verified":     true,
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"iat":                now.Unix(),
+		"iss":                KeycloakIssuer,
+		"jti":                "0f8ce5d4-9b4f-4b8e-8c4b-5ad6c1f8c0e1",
+		"preferred_username": KeycloakUsername,
+		"session_state":      KeycloakSessionState,
+		"sub":                KeycloakSubject,
+		"typ":                "ID",
+	}
+}
+
+// KeycloakRefreshToken reproduces the shape of a Keycloak refresh token, valid for thirty minutes.
+func KeycloakRefreshToken(now time.Time) tokens.Claims {
+	return tokens.Claims{
+		"aud":           KeycloakIssuer,
+		"azp":           KeycloakAzp,
+		"exp":           now.Add(30 * time.Minute).Unix(),
+		"iat":           now.Unix(),
+		"iss":           KeycloakIssuer,
+		"jti":           "b1a2c3d4-e5f6-4a7b-8c9d-0e1f2a3b4c5d",
+		"scope":         "profile email",
+		"session_state": KeycloakSessionState,
+		"sub":           KeycloakSubject,
+		"typ":           "Refresh",
+	}
+}
+
+// HydraAccessToken reproduces the shape of an ORY Hydra access token, valid for one hour.
+func HydraAccessToken(now time.Time) tokens.Claims {
+	return tokens.Claims{
+		"aud":       []interface{}{},
+		"client_id": HydraClientID,
+		"exp":       now.Add(time.Hour).Unix(),
+		"ext":       map[string]interface{}{},
+		"iat":       now.Unix(),
+		"iss":       HydraIssuer,
+		"jti":       "26c5cd47-a778-469d-9790-fd9ad5d47bcf",
+		"nbf":       now.Unix(),
+		"scp":       []interface{}{"openid", "offline"},
+		"sub":       HydraClientID,
+	}
+}
@@ -0,0 +1,46 @@
+package webfinger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOpenIDConfigurationHandler(t *testing.T) {
+	metadata := &OpenIDMetadata{
+		GrantTypesSupportedValue:              []string{"client_credentials"},
+		IDTokenSigningAlgValuesSupportedValue: []string{"RS256"},
+		MTLSEndpointAliasesValue:              &MTLSEndpointAliases{TokenEndpoint: "https://mtls.example.com/token"},
+		TokenEndpointValue:                    "https://example.com/token",
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/.well-known/openid-configuration", OpenIDConfigurationHandler(metadata, time.Hour))
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+	metadata.IssuerValue = testServer.URL
+	metadata.JWKSURIValue = testServer.URL + "/jwks"
+
+	openIDConfig, err := ResolveOpenIDConfiguration(testServer.URL)
+	if err != nil {
+		t.Fatalf("expected the served configuration to resolve but received '%v'", err)
+	}
+	if openIDConfig.Issuer() != testServer.URL || openIDConfig.JWKSURI() != testServer.URL+"/jwks" {
+		t.Fatalf("expected the issuer and jwks_uri but received '%s', '%s'", openIDConfig.Issuer(), openIDConfig.JWKSURI())
+	}
+	if grants := openIDConfig.GrantTypesSupported(); len(grants) != 1 || grants[0] != "client_credentials" {
+		t.Fatalf("expected the supported grant types but received '%v'", grants)
+	}
+	if endpoint := openIDConfig.MTLSTokenEndpoint(); endpoint != "https://mtls.example.com/token" {
+		t.Fatalf("expected the mtls token endpoint alias but received '%s'", endpoint)
+	}
+
+	resp, err := http.Post(testServer.URL+"/.well-known/openid-configuration", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 Method Not Allowed but received '%d'", resp.StatusCode)
+	}
+}
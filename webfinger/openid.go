@@ -14,6 +14,7 @@ import (
 type OpenIDConfiguration interface {
 	// endpoints:
 	AuthorizationEndpoint() string
+	DeviceAuthorizationEndpoint() string
 	EndSessionEndpoint() string
 	IntrospectionEndpoint() string
 	JWKSURI() string
@@ -26,6 +27,7 @@ type OpenIDConfiguration interface {
 	ClaimsSupported() []string
 	ClaimTypesSupported() []string
 	CodeChallengeMethodsSupported() []string
+	DPoPSigningAlgValuesSupported() []string
 	GrantTypesSupported() []string
 	IDTokenEncryptionEncValuesSupported() []string
 	IDTokenSigningAlgValuesSupported() []string
@@ -42,6 +44,8 @@ type OpenIDConfiguration interface {
 	// other:
 	CheckSessionIFrame() string
 	Issuer() string
+	MTLSEndpointAliases() *MTLSEndpointAliases
+	MTLSTokenEndpoint() string
 	TLSClientCertificateBoundAccessToken() bool
 	// utilities:
 	ResolveJWKS() (jwks.JWKS, error)
@@ -77,43 +81,63 @@ func ResolveOpenIDConfigurationWithHTTPClient(baseURL string, client *http.Clien
 	return openIDConfig, nil
 }
 
-// OpenIDConfiguration represents well known OpenID configuration.
+// OpenIDMetadata represents the well known OpenID configuration document.
+// It is decoded by ResolveOpenIDConfiguration and served by OpenIDConfigurationHandler.
+type OpenIDMetadata struct {
+	AuthorizationEndpointValue                      string               `json:"authorization_endpoint,omitempty"`
+	CheckSessionIFrameValue                         string               `json:"check_session_iframe,omitempty"`
+	ClaimsParameterSupportedValue                   bool                 `json:"claims_parameter_supported,omitempty"`
+	ClaimsSupportedValue                            []string             `json:"claims_supported,omitempty"`
+	ClaimTypesSupportedValue                        []string             `json:"claim_types_supported,omitempty"`
+	CodeChallengeMethodsSupportedValue              []string             `json:"code_challenge_methods_supported,omitempty"`
+	DeviceAuthorizationEndpointValue                string               `json:"device_authorization_endpoint,omitempty"`
+	DPoPSigningAlgValuesSupportedValue              []string             `json:"dpop_signing_alg_values_supported,omitempty"`
+	EndSessionEndpointValue                         string               `json:"end_session_endpoint,omitempty"`
+	GrantTypesSupportedValue                        []string             `json:"grant_types_supported,omitempty"`
+	IDTokenEncryptionEncValuesSupportedValue        []string             `json:"id_token_encryption_enc_values_supported,omitempty"`
+	IDTokenSigningAlgValuesSupportedValue           []string             `json:"id_token_signing_alg_values_supported,omitempty"`
+	IntrospectionEndpointValue                      string               `json:"introspection_endpoint,omitempty"`
+	IssuerValue                                     string               `json:"issuer,omitempty"`
+	JWKSURIValue                                    string               `json:"jwks_uri,omitempty"`
+	MTLSEndpointAliasesValue                        *MTLSEndpointAliases `json:"mtls_endpoint_aliases,omitempty"`
+	RegistrationEndpointValue                       string               `json:"registration_endpoint,omitempty"`
+	RequestObjectSigningAlgValuesSupportedValue     []string             `json:"request_object_signing_alg_values_supported,omitempty"`
+	RequestParameterSupportedValue                  bool                 `json:"request_parameter_supported,omitempty"`
+	RequestURIParameterSupportedValue               bool                 `json:"request_uri_parameter_supported,omitempty"`
+	ResponseModesSupportedValue                     []string             `json:"response_modes_supported,omitempty"`
+	ResponseTypesSupportedValue                     []string             `json:"response_types_supported,omitempty"`
+	ScopesSupportedValue                            []string             `json:"scopes_supported,omitempty"`
+	SubjectTypesSupportedValue                      []string             `json:"subject_types_supported,omitempty"`
+	TLSClientCertificateBoundAccessTokenValue       bool                 `json:"tls_client_certificate_bound_access_tokens,omitempty"`
+	TokenEndpointValue                              string               `json:"token_endpoint,omitempty"`
+	TokenEndpointAuthMethodsSupportedValue          []string             `json:"token_endpoint_auth_methods_supported,omitempty"`
+	TokenEndpointAuthSigningAlgValuesSupportedValue []string             `json:"token_endpoint_auth_signing_alg_values_supported,omitempty"`
+	TokenIntrospectionEndpointValue                 string               `json:"token_introspection_endpoint,omitempty"`
+	UserInfoEndpointValue                           string               `json:"userinfo_endpoint,omitempty"`
+	UserInfoSigningAlgValuesSupportedValue          []string             `json:"userinfo_signing_alg_values_supported,omitempty"`
+}
+
+// MTLSEndpointAliases represents the RFC 8705 endpoints clients call with mutual TLS.
+type MTLSEndpointAliases struct {
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint,omitempty"`
+	IntrospectionEndpoint       string `json:"introspection_endpoint,omitempty"`
+	RevocationEndpoint          string `json:"revocation_endpoint,omitempty"`
+	TokenEndpoint               string `json:"token_endpoint,omitempty"`
+	UserInfoEndpoint            string `json:"userinfo_endpoint,omitempty"`
+}
+
 type defaultOpenIDConfiguration struct {
-	AuthorizationEndpointValue                      string   `json:"authorization_endpoint"`
-	CheckSessionIFrameValue                         string   `json:"check_session_iframe"`
-	ClaimsParameterSupportedValue                   bool     `json:"claims_parameter_supported"`
-	ClaimsSupportedValue                            []string `json:"claims_supported"`
-	ClaimTypesSupportedValue                        []string `json:"claim_types_supported"`
-	CodeChallengeMethodsSupportedValue              []string `json:"code_challenge_methods_supported"`
-	EndSessionEndpointValue                         string   `json:"end_session_endpoint"`
-	GrantTypesSupportedValue                        []string `json:"grant_types_supported"`
-	IDTokenEncryptionEncValuesSupportedValue        []string `json:"id_token_encryption_enc_values_supported"`
-	IDTokenSigningAlgValuesSupportedValue           []string `json:"id_token_signing_alg_values_supported"`
-	IntrospectionEndpointValue                      string   `json:"introspection_endpoint"`
-	IssuerValue                                     string   `json:"issuer"`
-	JWKSURIValue                                    string   `json:"jwks_uri"`
-	RegistrationEndpointValue                       string   `json:"registration_endpoint"`
-	RequestObjectSigningAlgValuesSupportedValue     []string `json:"request_object_signing_alg_values_supported"`
-	RequestParameterSupportedValue                  bool     `json:"request_parameter_supported"`
-	RequestURIParameterSupportedValue               bool     `json:"request_uri_parameter_supported"`
-	ResponseModesSupportedValue                     []string `json:"response_modes_supported"`
-	ResponseTypesSupportedValue                     []string `json:"response_types_supported"`
-	ScopesSupportedValue                            []string `json:"scopes_supported"`
-	SubjectTypesSupportedValue                      []string `json:"subject_types_supported"`
-	TLSClientCertificateBoundAccessTokenValue       bool     `json:"tls_client_certificate_bound_access_tokens"`
-	TokenEndpointValue                              string   `json:"token_endpoint"`
-	TokenEndpointAuthMethodsSupportedValue          []string `json:"token_endpoint_auth_methods_supported"`
-	TokenEndpointAuthSigningAlgValuesSupportedValue []string `json:"token_endpoint_auth_signing_alg_values_supported"`
-	TokenIntrospectionEndpointValue                 string   `json:"token_introspection_endpoint"`
-	UserInfoEndpointValue                           string   `json:"userinfo_endpoint"`
-	UserInfoSigningAlgValuesSupportedValue          []string `json:"userinfo_signing_alg_values_supported"`
-	httpClient                                      *http.Client
+	OpenIDMetadata
+	httpClient *http.Client
 }
 
 // endpoints:
 func (c *defaultOpenIDConfiguration) AuthorizationEndpoint() string {
 	return c.AuthorizationEndpointValue
 }
+func (c *defaultOpenIDConfiguration) DeviceAuthorizationEndpoint() string {
+	return c.DeviceAuthorizationEndpointValue
+}
 func (c *defaultOpenIDConfiguration) EndSessionEndpoint() string {
 	return c.EndSessionEndpointValue
 }
@@ -149,6 +173,9 @@ func (c *defaultOpenIDConfiguration) ClaimTypesSupported() []string {
 func (c *defaultOpenIDConfiguration) CodeChallengeMethodsSupported() []string {
 	return c.CodeChallengeMethodsSupportedValue
 }
+func (c *defaultOpenIDConfiguration) DPoPSigningAlgValuesSupported() []string {
+	return c.DPoPSigningAlgValuesSupportedValue
+}
 func (c *defaultOpenIDConfiguration) GrantTypesSupported() []string {
 	return c.GrantTypesSupportedValue
 }
@@ -196,6 +223,17 @@ func (c *defaultOpenIDConfiguration) CheckSessionIFrame() string {
 func (c *defaultOpenIDConfiguration) Issuer() string {
 	return c.IssuerValue
 }
+func (c *defaultOpenIDConfiguration) MTLSEndpointAliases() *MTLSEndpointAliases {
+	return c.MTLSEndpointAliasesValue
+}
+
+// MTLSTokenEndpoint returns the mutual TLS token endpoint alias, the token endpoint without the alias.
+func (c *defaultOpenIDConfiguration) MTLSTokenEndpoint() string {
+	if c.MTLSEndpointAliasesValue != nil && c.MTLSEndpointAliasesValue.TokenEndpoint != "" {
+		return c.MTLSEndpointAliasesValue.TokenEndpoint
+	}
+	return c.TokenEndpointValue
+}
 func (c *defaultOpenIDConfiguration) TLSClientCertificateBoundAccessToken() bool {
 	return c.TLSClientCertificateBoundAccessTokenValue
 }
@@ -6,3 +6,33 @@ import "fmt"
 func KeycloakBaseURL(baseURL, realm string) string {
 	return fmt.Sprintf("%s/auth/realms/%s", baseURL, realm)
 }
+
+// Auth0BaseURL returns Auth0 specific webfinger base URL.
+func Auth0BaseURL(domain string) string {
+	return fmt.Sprintf("https://%s", domain)
+}
+
+// OktaBaseURL returns Okta specific webfinger base URL.
+// An empty authorization server ID selects the Okta org authorization server.
+func OktaBaseURL(domain, authorizationServerID string) string {
+	if authorizationServerID == "" {
+		return fmt.Sprintf("https://%s", domain)
+	}
+	return fmt.Sprintf("https://%s/oauth2/%s", domain, authorizationServerID)
+}
+
+// AzureADBaseURL returns Azure AD / Entra ID v2.0 specific webfinger base URL.
+// The tenant is a tenant ID, a verified domain or one of common, organizations and consumers.
+func AzureADBaseURL(tenant string) string {
+	return fmt.Sprintf("https://login.microsoftonline.com/%s/v2.0", tenant)
+}
+
+// CognitoBaseURL returns AWS Cognito user pool specific webfinger base URL.
+func CognitoBaseURL(region, userPoolID string) string {
+	return fmt.Sprintf("https://cognito-idp.%s.amazonaws.com/%s", region, userPoolID)
+}
+
+// GoogleBaseURL returns Google specific webfinger base URL.
+func GoogleBaseURL() string {
+	return "https://accounts.google.com"
+}